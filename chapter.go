@@ -0,0 +1,169 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// going to the previous chapter restarts the current chapter instead if it
+// has been played longer than this
+const chapterRestart = 3 * time.Second
+
+// tick on the progress bar where a chapter starts
+const chapterTick = "╋"
+
+func (p *PlayingBar) getChapters() []player.Chapter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.chapters
+}
+
+func (p *PlayingBar) setChapters(chapters []player.Chapter) {
+	p.mu.Lock()
+	p.chapters = chapters
+	p.chapter = -1
+	p.mu.Unlock()
+}
+
+// Shows the title of the chapter being played next to the song title
+func (p *PlayingBar) updateChapter(position time.Duration) {
+
+	p.mu.Lock()
+	chapters := p.chapters
+	current := player.ChapterAt(chapters, position)
+	changed := current != p.chapter
+	p.chapter = current
+	p.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	title := p.songName
+	if current >= 0 {
+		title = fmt.Sprintf("%s ─ %s", title, chapters[current].Title)
+	}
+
+	gomu.app.QueueUpdate(func() {
+		p.setSongTitle(title)
+	})
+}
+
+// Marks the start of each chapter on the progress bar
+func (p *PlayingBar) markChapters(progressBar string, full int) string {
+
+	chapters := p.getChapters()
+	if len(chapters) == 0 || full <= 0 {
+		return progressBar
+	}
+
+	bar := []rune(progressBar)
+	tick := []rune(chapterTick)[0]
+
+	for _, c := range chapters {
+		// the first chapter usually starts with the song
+		if c.Start <= 0 {
+			continue
+		}
+		index := int(c.Start.Seconds()) * len(bar) / full
+		if index < len(bar) {
+			bar[index] = tick
+		}
+	}
+
+	return string(bar)
+}
+
+// Seeks to the start of the chapter
+func seekChapter(c player.Chapter) error {
+
+	// rounds up so the position falls inside the chapter
+	position := int(math.Ceil(c.Start.Seconds()))
+
+	err := gomu.player.Seek(position)
+	if err != nil {
+		return err
+	}
+
+	gomu.playingBar.setProgress(position)
+
+	return nil
+}
+
+// Seeks to the chapter after the one being played
+func nextChapter() error {
+
+	chapters := gomu.playingBar.getChapters()
+	position := gomu.player.GetPosition()
+
+	var next *player.Chapter
+	for i, c := range chapters {
+		if c.Start > position && (next == nil || c.Start < next.Start) {
+			next = &chapters[i]
+		}
+	}
+
+	if next == nil {
+		return nil
+	}
+
+	return seekChapter(*next)
+}
+
+// Seeks to the start of the chapter being played or to the previous chapter
+// if the current one has just started
+func prevChapter() error {
+
+	chapters := gomu.playingBar.getChapters()
+	position := gomu.player.GetPosition()
+
+	current := player.ChapterAt(chapters, position)
+	if current < 0 {
+		return nil
+	}
+
+	if position-chapters[current].Start > chapterRestart {
+		return seekChapter(chapters[current])
+	}
+
+	prev := player.ChapterAt(chapters, chapters[current].Start-1)
+	if prev < 0 {
+		prev = current
+	}
+
+	return seekChapter(chapters[prev])
+}
+
+// Lists the chapters of the current song and seeks to the selected one
+func chapterPopup() {
+
+	chapters := gomu.playingBar.getChapters()
+	if len(chapters) == 0 {
+		defaultTimedPopup(" Chapters ", "No chapters in this song")
+		return
+	}
+
+	titles := make([]string, len(chapters))
+	for i, c := range chapters {
+		// start time keeps the titles unique
+		titles[i] = fmt.Sprintf("%s %s", fmtDuration(c.Start), c.Title)
+	}
+
+	searchPopup(" Chapters ", titles, func(selected string) {
+		for i, title := range titles {
+			if title != selected {
+				continue
+			}
+			err := seekChapter(chapters[i])
+			if err != nil {
+				errorPopup(err)
+			}
+			return
+		}
+	})
+}
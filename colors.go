@@ -20,6 +20,8 @@ type Colors struct {
 	playlistDir tcell.Color
 	queueHi     tcell.Color
 	subtitle    string
+	// color of marked rows in the queue
+	queueMark string
 }
 
 func init() {
@@ -38,6 +40,7 @@ func newColor() *Colors {
 		"Color.queue_highlight":    "darkcyan",
 		"Color.now_playing_title":  "darkgreen",
 		"Color.subtitle":           "darkgoldenrod",
+		"Color.queue_mark":         "yellow",
 	}
 
 	anko := gomu.anko
@@ -63,6 +66,7 @@ func newColor() *Colors {
 	queueHi := anko.GetString("Color.queue_highlight")
 	title := anko.GetString("Color.now_playing_title")
 	subtitle := anko.GetString("Color.subtitle")
+	queueMark := anko.GetString("Color.queue_mark")
 
 	color := &Colors{
 		accent:      tcell.ColorNames[accent],
@@ -74,6 +78,7 @@ func newColor() *Colors {
 		queueHi:     tcell.ColorNames[queueHi],
 		title:       tcell.ColorNames[title],
 		subtitle:    subtitle,
+		queueMark:   queueMark,
 	}
 	return color
 }
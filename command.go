@@ -1,7 +1,11 @@
 package main
 
 import (
+	"fmt"
+	"math"
+	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/issadarkthing/gomu/player"
 	"github.com/rivo/tview"
@@ -46,7 +50,7 @@ func (c Command) defineCommands() {
 
 	c.define("delete_playlist", func() {
 		audioFile := gomu.playlist.getCurrentFile()
-		if audioFile.IsAudioFile() {
+		if audioFile.IsAudioFile() || isStation(audioFile) || inPlaylistFile(audioFile) {
 			return
 		}
 		err := confirmDeleteAllPopup(audioFile.Node())
@@ -58,7 +62,8 @@ func (c Command) defineCommands() {
 	c.define("delete_file", func() {
 		audioFile := gomu.playlist.getCurrentFile()
 		// prevent from deleting a directory
-		if !audioFile.IsAudioFile() {
+		if !audioFile.IsAudioFile() || isStation(audioFile) || isCueTrack(audioFile) ||
+			inPlaylistFile(audioFile) {
 			return
 		}
 
@@ -79,6 +84,9 @@ func (c Command) defineCommands() {
 			gomu.popups.pop()
 			return
 		}
+		if isStation(audioFile) || inPlaylistFile(audioFile) {
+			return
+		}
 		// this ensures it downloads to
 		// the correct dir
 		if audioFile.IsAudioFile() {
@@ -92,7 +100,9 @@ func (c Command) defineCommands() {
 		audioFile := gomu.playlist.getCurrentFile()
 		currNode := gomu.playlist.GetCurrentNode()
 		if audioFile.IsAudioFile() {
-			gomu.queue.pushFront(audioFile)
+			gomu.queue.record("add", func() {
+				gomu.queue.pushFront(audioFile)
+			})
 			if len(gomu.queue.items) == 1 && !gomu.player.IsRunning() {
 				err := gomu.queue.playQueue()
 				if err != nil {
@@ -104,6 +114,56 @@ func (c Command) defineCommands() {
 		}
 	})
 
+	c.define("play_next", func() {
+		audioFile := gomu.playlist.getCurrentFile()
+		if !audioFile.IsAudioFile() {
+			return
+		}
+		var err error
+		gomu.queue.record("play next", func() {
+			err = gomu.queue.playNext([]*player.AudioFile{audioFile})
+		})
+		if err != nil {
+			errorPopup(err)
+		}
+	})
+
+	c.define("play_now", func() {
+		audioFile := gomu.playlist.getCurrentFile()
+		if !audioFile.IsAudioFile() {
+			return
+		}
+		var err error
+		gomu.queue.record("play now", func() {
+			err = gomu.queue.playNow([]*player.AudioFile{audioFile})
+		})
+		if err != nil {
+			errorPopup(err)
+		}
+	})
+
+	c.define("bulk_play_next", func() {
+		songs := bulkSongs(gomu.playlist.GetCurrentNode())
+		var err error
+		gomu.queue.record("play next", func() {
+			err = gomu.queue.playNext(songs)
+		})
+		if err != nil {
+			errorPopup(err)
+		}
+	})
+
+	c.define("bulk_play_now", func() {
+		songs := bulkSongs(gomu.playlist.GetCurrentNode())
+		var err error
+		gomu.queue.record("play now", func() {
+			err = gomu.queue.playNow(songs)
+		})
+		if err != nil {
+			errorPopup(err)
+		}
+	})
+
 	c.define("close_node", func() {
 		audioFile := gomu.playlist.getCurrentFile()
 		currNode := gomu.playlist.GetCurrentNode()
@@ -124,7 +184,9 @@ func (c Command) defineCommands() {
 		bulkAdd := anko.GetBool("General.confirm_bulk_add")
 
 		if !bulkAdd {
-			gomu.playlist.addAllToQueue(currNode)
+			gomu.queue.record("add", func() {
+				gomu.playlist.addAllToQueue(currNode)
+			})
 			if len(gomu.queue.items) > 0 && !gomu.player.IsRunning() {
 				err := gomu.queue.playQueue()
 				if err != nil {
@@ -139,7 +201,9 @@ func (c Command) defineCommands() {
 			func(_ int, label string) {
 
 				if label == "yes" {
-					gomu.playlist.addAllToQueue(currNode)
+					gomu.queue.record("add", func() {
+						gomu.playlist.addAllToQueue(currNode)
+					})
 					if len(gomu.queue.items) > 0 && !gomu.player.IsRunning() {
 						err := gomu.queue.playQueue()
 						if err != nil {
@@ -157,6 +221,9 @@ func (c Command) defineCommands() {
 
 	c.define("rename", func() {
 		audioFile := gomu.playlist.getCurrentFile()
+		if isStation(audioFile) || isCueTrack(audioFile) || inPlaylistFile(audioFile) {
+			return
+		}
 		renamePopup(audioFile)
 	})
 
@@ -187,6 +254,16 @@ func (c Command) defineCommands() {
 			errorPopup(err)
 		}
 
+		configurePlayer()
+
+		err = applyEqualizerConfig()
+		if err == nil {
+			err = saveEqualizer()
+		}
+		if err != nil {
+			errorPopup(err)
+		}
+
 		infoPopup("successfully reload config file")
 	})
 
@@ -201,14 +278,57 @@ func (c Command) defineCommands() {
 	})
 
 	c.define("delete_item", func() {
-		gomu.queue.deleteItem(gomu.queue.GetCurrentItem())
+		gomu.queue.record("delete", gomu.queue.deleteSelection)
+	})
+
+	c.define("toggle_visual", func() {
+		gomu.queue.toggleVisual()
+	})
+
+	c.define("toggle_mark", func() {
+		gomu.queue.toggleMark()
+	})
+
+	c.define("clear_selection", func() {
+		gomu.queue.clearSelection()
+	})
+
+	c.define("move_item_down", func() {
+		gomu.queue.record("move", func() {
+			gomu.queue.moveSelection(true)
+		})
+	})
+
+	c.define("move_item_up", func() {
+		gomu.queue.record("move", func() {
+			gomu.queue.moveSelection(false)
+		})
+	})
+
+	c.define("send_to_playlist", func() {
+		songs := gomu.queue.selectedSongs()
+		if len(songs) == 0 {
+			return
+		}
+		inputPopup("Add to playlist", "playlist.m3u8", func(name string) {
+			playlistPath := playlistFilePath(name)
+			err := appendPlaylistFile(playlistPath, songs)
+			if err != nil {
+				errorPopup(err)
+				return
+			}
+			gomu.queue.clearSelection()
+			defaultTimedPopup(" Success ",
+				fmt.Sprintf("%d songs have been added to\n%s", len(songs), playlistPath))
+			gomu.playlist.refresh()
+		})
 	})
 
 	c.define("clear_queue", func() {
 		confirmationPopup("Are you sure to clear the queue?",
 			func(_ int, label string) {
 				if label == "yes" {
-					gomu.queue.clearQueue()
+					gomu.queue.record("clear", gomu.queue.clearQueue)
 				}
 			})
 	})
@@ -231,12 +351,170 @@ func (c Command) defineCommands() {
 	})
 
 	c.define("toggle_loop", func() {
-		gomu.queue.isLoop = !gomu.queue.isLoop
-		gomu.queue.updateTitle()
+		gomu.queue.setRepeat(gomu.queue.repeat.next())
+	})
+
+	c.define("toggle_stop_after", func() {
+		gomu.queue.toggleStopAfterCurrent()
 	})
 
 	c.define("shuffle_queue", func() {
-		gomu.queue.shuffle()
+		gomu.queue.record("shuffle", gomu.queue.shuffle)
+	})
+
+	c.define("shuffle_artist", func() {
+		gomu.queue.record("shuffle", func() {
+			gomu.queue.shuffleBy(shuffleArtist, time.Now().UnixNano())
+		})
+	})
+
+	c.define("shuffle_album", func() {
+		gomu.queue.record("shuffle", func() {
+			gomu.queue.shuffleBy(shuffleAlbum, time.Now().UnixNano())
+		})
+	})
+
+	c.define("unshuffle", func() {
+		var ok bool
+		gomu.queue.record("unshuffle", func() {
+			ok = gomu.queue.unshuffle()
+		})
+		if !ok {
+			defaultTimedPopup(" Shuffle ", "Nothing to undo")
+		}
+	})
+
+	c.define("undo", func() {
+		if _, ok := gomu.queue.undo(); !ok {
+			defaultTimedPopup(" Undo ", "Nothing to undo")
+		}
+	})
+
+	c.define("redo", func() {
+		if _, ok := gomu.queue.redo(); !ok {
+			defaultTimedPopup(" Redo ", "Nothing to redo")
+		}
+	})
+
+	c.define("export_queue", func() {
+		if len(gomu.queue.items) == 0 {
+			defaultTimedPopup(" Export Queue ", "Queue is empty")
+			return
+		}
+		inputPopup("Save queue as", "queue.m3u8", func(name string) {
+			playlistPath := playlistFilePath(name)
+			err := gomu.queue.exportPlaylist(playlistPath)
+			if err != nil {
+				errorPopup(err)
+				return
+			}
+			defaultTimedPopup(" Success ", "Queue has been saved to\n"+playlistPath)
+			gomu.playlist.refresh()
+		})
+	})
+
+	c.define("save_snapshot", func() {
+		inputPopup("Save queue as", "", func(name string) {
+			err := gomu.queue.saveSnapshot(name)
+			if err != nil {
+				errorPopup(err)
+				return
+			}
+			defaultTimedPopup(" Success ", "Queue has been saved as "+name)
+		})
+	})
+
+	c.define("load_snapshot", func() {
+		snapshotPopup(" Load Queue ", func(name string) {
+			var missing int
+			var err error
+			gomu.queue.record("load", func() {
+				missing, err = gomu.queue.loadSnapshot(name)
+			})
+			if err != nil {
+				errorPopup(err)
+				return
+			}
+			if missing > 0 {
+				defaultTimedPopup(" Load Queue ",
+					fmt.Sprintf("%d songs of %s could not be found", missing, name))
+			}
+			if len(gomu.queue.items) > 0 && !gomu.player.IsRunning() {
+				err := gomu.queue.playQueue()
+				if err != nil {
+					errorPopup(err)
+				}
+			}
+		})
+	})
+
+	c.define("rename_snapshot", func() {
+		snapshotPopup(" Rename Queue ", func(name string) {
+			inputPopup("Rename "+name+" to", name, func(newName string) {
+				err := renameSnapshot(name, newName)
+				if err != nil {
+					errorPopup(err)
+				}
+			})
+		})
+	})
+
+	c.define("delete_snapshot", func() {
+		snapshotPopup(" Delete Queue ", func(name string) {
+			confirmationPopup("Delete saved queue "+name+"?", func(_ int, label string) {
+				if label != "yes" {
+					return
+				}
+				err := deleteSnapshot(name)
+				if err != nil {
+					errorPopup(err)
+				}
+			})
+		})
+	})
+
+	c.define("import_queue", func() {
+		musicDir := expandTilde(gomu.anko.GetString("General.music_dir"))
+		files := gomu.playlist.playlistFiles()
+		if len(files) == 0 {
+			defaultTimedPopup(" Import Playlist ", "No playlist files found")
+			return
+		}
+
+		names := make([]string, len(files))
+		for i, file := range files {
+			names[i] = file
+			if rel, err := filepath.Rel(musicDir, file); err == nil {
+				names[i] = rel
+			}
+		}
+
+		searchPopup(" Import Playlist ", names, func(selected string) {
+			for i, name := range names {
+				if name != selected {
+					continue
+				}
+				var unresolved []string
+				var err error
+				gomu.queue.record("import", func() {
+					unresolved, err = gomu.queue.importPlaylist(files[i])
+				})
+				if err != nil {
+					errorPopup(err)
+					return
+				}
+				if len(unresolved) > 0 {
+					unresolvedPopup(" Import Playlist ", unresolved)
+				}
+				if len(gomu.queue.items) > 0 && !gomu.player.IsRunning() {
+					err := gomu.queue.playQueue()
+					if err != nil {
+						errorPopup(err)
+					}
+				}
+				return
+			}
+		})
 	})
 
 	c.define("queue_search", func() {
@@ -295,10 +573,38 @@ func (c Command) defineCommands() {
 		}
 	})
 
+	c.define("speed_up", func() {
+		speed := gomu.player.GetSpeed() + 0.1
+		speedPopup(gomu.player.SetSpeed(math.Round(speed*10) / 10))
+	})
+
+	c.define("speed_down", func() {
+		speed := gomu.player.GetSpeed() - 0.1
+		speedPopup(gomu.player.SetSpeed(math.Round(speed*10) / 10))
+	})
+
+	c.define("speed_reset", func() {
+		speedPopup(gomu.player.SetSpeed(1))
+	})
+
+	c.define("sleep_timer", func() {
+		name, _ := gomu.pages.GetFrontPage()
+		if name != "sleep-timer-input-popup" {
+			sleepTimerPopup()
+		}
+	})
+
 	c.define("skip", func() {
 		gomu.player.Skip()
 	})
 
+	c.define("prev", func() {
+		err := playPrev()
+		if err != nil {
+			errorPopup(err)
+		}
+	})
+
 	c.define("toggle_help", func() {
 		name, _ := gomu.pages.GetFrontPage()
 
@@ -390,6 +696,69 @@ func (c Command) defineCommands() {
 		}
 	})
 
+	c.define("next_chapter", func() {
+		if gomu.player.State() == player.Stopped {
+			return
+		}
+		err := nextChapter()
+		if err != nil {
+			errorPopup(err)
+		}
+	})
+
+	c.define("prev_chapter", func() {
+		if gomu.player.State() == player.Stopped {
+			return
+		}
+		err := prevChapter()
+		if err != nil {
+			errorPopup(err)
+		}
+	})
+
+	c.define("chapters", func() {
+		if gomu.player.State() == player.Stopped {
+			return
+		}
+		chapterPopup()
+	})
+
+	c.define("loop_a", func() {
+		if !gomu.player.IsRunning() && !gomu.player.IsPaused() {
+			return
+		}
+		// setting point A starts a new loop
+		gomu.player.ClearLoop()
+		position := gomu.player.GetPosition()
+		gomu.playingBar.setLoopStart(position)
+		defaultTimedPopup(" Loop ", "Point A set at "+fmtDuration(position))
+	})
+
+	c.define("loop_b", func() {
+		if !gomu.player.IsRunning() && !gomu.player.IsPaused() {
+			return
+		}
+		start, ok := gomu.playingBar.getLoopStart()
+		if !ok {
+			defaultTimedPopup(" Loop ", "Set point A first")
+			return
+		}
+		end := gomu.player.GetPosition()
+		err := gomu.player.SetLoop(start, end)
+		if err != nil {
+			errorPopup(err)
+			return
+		}
+		defaultTimedPopup(" Loop ", fmt.Sprintf("Looping %s - %s",
+			fmtDuration(start), fmtDuration(end)))
+	})
+
+	c.define("clear_loop", func() {
+		gomu.player.ClearLoop()
+		gomu.playingBar.clearLoopStart()
+		defaultTimedPopup(" Loop ", "Loop cleared")
+	})
+
 	c.define("yank", func() {
 		err := gomu.playlist.yank()
 		if err != nil {
@@ -410,12 +779,31 @@ func (c Command) defineCommands() {
 
 	c.define("edit_tags", func() {
 		audioFile := gomu.playlist.getCurrentFile()
+		if isStation(audioFile) || isCueTrack(audioFile) {
+			return
+		}
 		err := tagPopup(audioFile)
 		if err != nil {
 			errorPopup(err)
 		}
 	})
 
+	c.define("scan_loudness", func() {
+		audioFile := gomu.playlist.getCurrentFile()
+		currNode := gomu.playlist.GetCurrentNode()
+		// scan the whole playlist the song belongs to
+		if audioFile.IsAudioFile() {
+			currNode = audioFile.ParentNode()
+		}
+
+		go func() {
+			err := gomu.playlist.scanLoudness(currNode)
+			if err != nil {
+				errorPopup(err)
+			}
+		}()
+	})
+
 	c.define("switch_lyric", func() {
 		gomu.playingBar.switchLyrics()
 	})
@@ -426,7 +814,7 @@ func (c Command) defineCommands() {
 
 		var wg sync.WaitGroup
 		wg.Add(1)
-		if audioFile.IsAudioFile() {
+		if audioFile.IsAudioFile() && !isStation(audioFile) && !isCueTrack(audioFile) {
 			go func() {
 				err := lyricPopup(lang, audioFile, &wg)
 				if err != nil {
@@ -442,7 +830,7 @@ func (c Command) defineCommands() {
 
 		var wg sync.WaitGroup
 		wg.Add(1)
-		if audioFile.IsAudioFile() {
+		if audioFile.IsAudioFile() && !isStation(audioFile) && !isCueTrack(audioFile) {
 			go func() {
 				err := lyricPopup(lang, audioFile, &wg)
 				if err != nil {
@@ -466,6 +854,17 @@ func (c Command) defineCommands() {
 		}
 	})
 
+	c.define("toggle_visualizer", func() {
+		gomu.playingBar.setVisualizer(!gomu.playingBar.isVisualizerOn())
+	})
+
+	c.define("equalizer", func() {
+		name, _ := gomu.pages.GetFrontPage()
+		if name != "equalizer-popup" {
+			equalizerPopup()
+		}
+	})
+
 	c.define("show_colors", func() {
 		cp := colorsPopup()
 		gomu.pages.AddPage("show-color-popup", center(cp, 95, 40), true, true)
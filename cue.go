@@ -0,0 +1,336 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/ztrue/tracerr"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// cue sheet time is counted in frames, there are 75 frames in a second
+const cueFramesPerSecond = 75
+
+// cueSheet describes how audio files are split into tracks
+type cueSheet struct {
+	title     string
+	performer string
+	tracks    []cueTrack
+}
+
+// cueTrack is a part of the audio file, zero end is the end of the file
+type cueTrack struct {
+	number    int
+	title     string
+	performer string
+	file      string
+	start     time.Duration
+	end       time.Duration
+}
+
+// Checks if the file is a cue sheet
+func isCueSheet(fileName string) bool {
+	return strings.EqualFold(filepath.Ext(fileName), ".cue")
+}
+
+// Checks if the audio file is a track of cue sheet, it shares the file with
+// the other tracks so the file must not be changed through it
+func isCueTrack(audioFile *player.AudioFile) bool {
+
+	if audioFile == nil {
+		return false
+	}
+
+	_, _, ok := audioFile.Bounds()
+	return ok
+}
+
+// Parses cue sheet, only the audio tracks which have their start index are
+// returned
+func parseCue(r io.Reader) (cueSheet, error) {
+
+	var sheet cueSheet
+	var file string
+	// track being parsed, nil when it is not an audio track
+	var track *cueTrack
+	var hasStart bool
+
+	addTrack := func() {
+		if track != nil && hasStart {
+			sheet.tracks = append(sheet.tracks, *track)
+		}
+		track = nil
+		hasStart = false
+	}
+
+	scanner := bufio.NewScanner(r)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+
+		line := strings.TrimPrefix(scanner.Text(), "\ufeff")
+		fields := cueFields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		args := fields[1:]
+
+		switch strings.ToUpper(fields[0]) {
+		case "FILE":
+			if len(args) == 0 {
+				return cueSheet{}, tracerr.Errorf("missing file name at line %d", lineNumber)
+			}
+			addTrack()
+			file = args[0]
+
+		case "TRACK":
+			addTrack()
+			if len(args) < 2 || !strings.EqualFold(args[1], "AUDIO") {
+				continue
+			}
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return cueSheet{}, tracerr.Errorf("invalid track number at line %d: %s", lineNumber, args[0])
+			}
+			if file == "" {
+				return cueSheet{}, tracerr.Errorf("track without file at line %d", lineNumber)
+			}
+			track = &cueTrack{number: number, file: file, performer: sheet.performer}
+
+		case "TITLE":
+			if len(args) == 0 {
+				continue
+			}
+			if track != nil {
+				track.title = args[0]
+			} else if file == "" {
+				sheet.title = args[0]
+			}
+
+		case "PERFORMER":
+			if len(args) == 0 {
+				continue
+			}
+			if track != nil {
+				track.performer = args[0]
+			} else if file == "" {
+				sheet.performer = args[0]
+			}
+
+		case "INDEX":
+			// index 00 is the pregap which belongs to the previous track
+			if track == nil || len(args) < 2 || args[0] != "01" {
+				continue
+			}
+			start, err := parseCueTime(args[1])
+			if err != nil {
+				return cueSheet{}, tracerr.Errorf("invalid index at line %d: %v", lineNumber, err)
+			}
+			track.start = start
+			hasStart = true
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return cueSheet{}, tracerr.Wrap(err)
+	}
+
+	addTrack()
+
+	// each track ends where the next track of the same file starts
+	for i := 0; i+1 < len(sheet.tracks); i++ {
+		next := sheet.tracks[i+1]
+		if next.file == sheet.tracks[i].file {
+			sheet.tracks[i].end = next.start
+		}
+	}
+
+	return sheet, nil
+}
+
+// Splits the line into command and arguments, quoted argument may contain
+// spaces
+func cueFields(line string) []string {
+
+	var fields []string
+	var field strings.Builder
+	var quoted, inField bool
+
+	for _, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+			inField = true
+		case !quoted && (c == ' ' || c == '\t'):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(c)
+			inField = true
+		}
+	}
+
+	if inField {
+		fields = append(fields, field.String())
+	}
+
+	return fields
+}
+
+// Parses time of cue sheet in the form of mm:ss:ff
+func parseCueTime(s string) (time.Duration, error) {
+
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid time %s", s)
+	}
+
+	var values [3]int
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("invalid time %s", s)
+		}
+		values[i] = v
+	}
+
+	frames := (values[0]*60+values[1])*cueFramesPerSecond + values[2]
+
+	return time.Duration(frames) * time.Second / cueFramesPerSecond, nil
+}
+
+// Loads cue sheet and resolves the paths of its audio files. Rips are often
+// converted after the sheet is made, so a supported file with the same name
+// is used when the file does not exist.
+func loadCue(cuePath string) (cueSheet, error) {
+
+	f, err := os.Open(cuePath)
+	if err != nil {
+		return cueSheet{}, tracerr.Wrap(err)
+	}
+	defer f.Close()
+
+	sheet, err := parseCue(f)
+	if err != nil {
+		return cueSheet{}, tracerr.Wrap(err)
+	}
+
+	dir := filepath.Dir(cuePath)
+	resolved := make(map[string]string)
+	var tracks []cueTrack
+
+	for _, track := range sheet.tracks {
+
+		audioPath, ok := resolved[track.file]
+		if !ok {
+			audioPath, err = resolveCueFile(dir, track.file)
+			if err != nil {
+				logError(err)
+			}
+			resolved[track.file] = audioPath
+		}
+
+		if audioPath == "" {
+			continue
+		}
+
+		track.file = audioPath
+		tracks = append(tracks, track)
+	}
+
+	sheet.tracks = tracks
+
+	return sheet, nil
+}
+
+// Gets path of the audio file referred by cue sheet
+func resolveCueFile(dir, name string) (string, error) {
+
+	candidates := []string{filepath.Join(dir, name)}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", tracerr.Wrap(err)
+	}
+
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	for _, file := range files {
+		if strings.TrimSuffix(file.Name(), filepath.Ext(file.Name())) == base {
+			candidates = append(candidates, filepath.Join(dir, file.Name()))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if !player.IsSupported(candidate) {
+			continue
+		}
+		path, err := filepath.EvalSymlinks(candidate)
+		if err != nil {
+			continue
+		}
+		return path, nil
+	}
+
+	return "", tracerr.Errorf("audio file of cue sheet not found: %s", name)
+}
+
+// Adds the tracks of cue sheet as the children of root
+func addCueTracks(root *tview.TreeNode, sheet cueSheet) {
+
+	// length of the last track depends on the length of the file
+	fileLengths := make(map[string]time.Duration)
+
+	for _, track := range sheet.tracks {
+
+		length := track.end - track.start
+		if track.end == 0 {
+			fileLength, ok := fileLengths[track.file]
+			if !ok {
+				var err error
+				fileLength, err = getTagLength(track.file)
+				if err != nil {
+					logError(err)
+				}
+				fileLengths[track.file] = fileLength
+			}
+			length = fileLength - track.start
+		}
+
+		name := track.title
+		if name == "" {
+			name = fmt.Sprintf("Track %02d", track.number)
+		}
+		if track.performer != "" {
+			name = track.performer + " - " + name
+		}
+
+		child := tview.NewTreeNode(name)
+
+		audioFile := new(player.AudioFile)
+		audioFile.SetName(name)
+		audioFile.SetPath(track.file)
+		audioFile.SetIsAudioFile(true)
+		audioFile.SetBounds(track.start, track.end)
+		audioFile.SetLen(length)
+		audioFile.SetNode(child)
+		audioFile.SetParentNode(root)
+
+		child.SetReference(audioFile)
+		child.SetText(setDisplayText(audioFile))
+		root.AddChild(child)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+const testCue = `REM GENRE Rock
+PERFORMER "The Band"
+TITLE "Live Album"
+FILE "album.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "Opening"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Encore"
+    PERFORMER "Guest"
+    INDEX 00 01:59:00
+    INDEX 01 02:00:37
+`
+
+func TestParseCue(t *testing.T) {
+
+	sheet, err := parseCue(strings.NewReader(testCue))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Live Album", sheet.title)
+	assert.Equal(t, "The Band", sheet.performer)
+	assert.Equal(t, []cueTrack{
+		{
+			number:    1,
+			title:     "Opening",
+			performer: "The Band",
+			file:      "album.wav",
+			start:     0,
+			end:       2*time.Minute + 37*time.Second/75,
+		},
+		{
+			number:    2,
+			title:     "Encore",
+			performer: "Guest",
+			file:      "album.wav",
+			start:     2*time.Minute + 37*time.Second/75,
+		},
+	}, sheet.tracks)
+
+	_, err = parseCue(strings.NewReader("TRACK 01 AUDIO"))
+	assert.Error(t, err)
+
+	_, err = parseCue(strings.NewReader("FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 1:2"))
+	assert.Error(t, err)
+}
+
+func TestCueFields(t *testing.T) {
+	assert.Equal(t,
+		[]string{"FILE", "my album.wav", "WAVE"},
+		cueFields(`  FILE "my album.wav"	WAVE`))
+	assert.Equal(t, []string{"TITLE", ""}, cueFields(`TITLE ""`))
+	assert.Nil(t, cueFields("   "))
+}
+
+func TestPopulateCue(t *testing.T) {
+
+	gomu = newGomu()
+	err := execConfig(expandFilePath(testConfigPath))
+	if err != nil {
+		t.Error(err)
+	}
+	gomu.colors = newColor()
+
+	dir := t.TempDir()
+	copyTestFile(t, "./test/rap/audio_test.mp3", filepath.Join(dir, "album.mp3"))
+
+	// the sheet was made for the wav before it was converted
+	cue := strings.Replace(testCue, "INDEX 01 02:00:37", "INDEX 01 00:01:00", 1)
+	err = ioutil.WriteFile(filepath.Join(dir, "album.cue"), []byte(cue), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := tview.NewTreeNode("music")
+	assert.NoError(t, populate(root, dir, false))
+
+	// the file is replaced by its tracks
+	children := root.GetChildren()
+	assert.Len(t, children, 2)
+
+	first := children[0].GetReference().(*player.AudioFile)
+	assert.Equal(t, "The Band - Opening", first.Name())
+	assert.Equal(t, time.Second, first.Len())
+	assert.True(t, isCueTrack(first))
+
+	second := children[1].GetReference().(*player.AudioFile)
+	assert.Equal(t, "Guest - Encore", second.Name())
+	start, end, _ := second.Bounds()
+	assert.Equal(t, time.Second, start)
+	assert.Zero(t, end)
+	assert.Equal(t, filepath.Join(dir, "album.mp3"), second.Path())
+}
+
+func copyTestFile(t *testing.T, src, dst string) {
+
+	content, err := ioutil.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(dst, content, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,359 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/ztrue/tracerr"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// customPreset is the name shown when the gains do not match any preset
+const customPreset = "custom"
+
+// equalizerSettings is saved to the cache so the equalizer is restored on the
+// next start.
+type equalizerSettings struct {
+	Preset string    `json:"preset"`
+	Bands  []float64 `json:"bands"`
+}
+
+// Gets equalizer cache path
+func equalizerCachePath() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		logError(err)
+	}
+	return filepath.Join(cacheDir, "gomu", "equalizer.cache")
+}
+
+// Converts anko list of numbers to float64
+func toFloats(value interface{}) ([]float64, error) {
+
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, tracerr.Errorf("expected list of numbers, got %v", value)
+	}
+
+	result := make([]float64, 0, len(list))
+	for _, v := range list {
+		switch n := v.(type) {
+		case int64:
+			result = append(result, float64(n))
+		case float64:
+			result = append(result, n)
+		default:
+			return nil, tracerr.Errorf("expected number, got %v", v)
+		}
+	}
+
+	return result, nil
+}
+
+// Gets equalizer presets from config file
+func getEqualizerPresets() map[string][]float64 {
+
+	presets := make(map[string][]float64)
+
+	value, err := gomu.anko.Execute("Equalizer.presets")
+	if err != nil {
+		logError(err)
+		return presets
+	}
+
+	m, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return presets
+	}
+
+	for name, bands := range m {
+		gains, err := toFloats(bands)
+		if err != nil {
+			logError(tracerr.Errorf("invalid equalizer preset %v: %w", name, err))
+			continue
+		}
+		presets[fmt.Sprint(name)] = gains
+	}
+
+	return presets
+}
+
+// Gets equalizer settings from config file, the preset takes precedence over
+// the bands
+func getEqualizerConfig() (equalizerSettings, error) {
+
+	preset := gomu.anko.GetString("Equalizer.preset")
+	if preset != "" {
+		return equalizerSettings{Preset: preset}, nil
+	}
+
+	value, err := gomu.anko.Execute("Equalizer.bands")
+	if err != nil {
+		return equalizerSettings{}, tracerr.Wrap(err)
+	}
+
+	bands, err := toFloats(value)
+	if err != nil {
+		return equalizerSettings{}, tracerr.Wrap(err)
+	}
+
+	return equalizerSettings{Bands: bands}, nil
+}
+
+// Applies the equalizer settings to the player. Named preset is looked up from
+// config file.
+func setEqualizer(settings equalizerSettings) error {
+
+	bands := settings.Bands
+
+	if settings.Preset != "" && settings.Preset != customPreset {
+		gains, ok := getEqualizerPresets()[settings.Preset]
+		if !ok {
+			return tracerr.Errorf("equalizer preset not found: %s", settings.Preset)
+		}
+		bands = gains
+	}
+
+	err := gomu.player.SetEqualizer(bands)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// Name of the preset matching the current gains
+func currentEqualizerPreset() string {
+
+	gains := gomu.player.GetEqualizer()
+	presets := getEqualizerPresets()
+
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+Presets:
+	for _, name := range names {
+		preset, err := player.EqualizerGains(presets[name])
+		if err != nil {
+			continue
+		}
+		for i := range preset {
+			if preset[i] != gains[i] {
+				continue Presets
+			}
+		}
+		return name
+	}
+
+	return customPreset
+}
+
+// Saves the current equalizer to the cache
+func saveEqualizer() error {
+
+	settings := equalizerSettings{
+		Preset: currentEqualizerPreset(),
+		Bands:  gomu.player.GetEqualizer(),
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	cachePath := equalizerCachePath()
+
+	err = os.MkdirAll(filepath.Dir(cachePath), 0755)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	err = os.WriteFile(cachePath, data, 0644)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// Restores the equalizer from the cache, falls back to config file if the
+// equalizer has never been changed
+func loadEqualizer() error {
+
+	data, err := os.ReadFile(equalizerCachePath())
+	if os.IsNotExist(err) {
+		return applyEqualizerConfig()
+	} else if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	var settings equalizerSettings
+	err = json.Unmarshal(data, &settings)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	// preset could be removed from config file
+	err = setEqualizer(settings)
+	if err != nil && settings.Preset != "" {
+		settings.Preset = ""
+		err = setEqualizer(settings)
+	}
+
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// Applies the equalizer from config file, this overrides the cache
+func applyEqualizerConfig() error {
+
+	settings, err := getEqualizerConfig()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	err = setEqualizer(settings)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// Shows a slider for each equalizer band
+func equalizerPopup() {
+
+	popupID := "equalizer-popup"
+	// slider length in characters, each step is 1dB
+	maxLength := int(player.MaxEqualizerGain - player.MinEqualizerGain)
+
+	textView := tview.NewTextView().
+		SetDynamicColors(true)
+
+	selected := 0
+
+	draw := func() {
+
+		gains := gomu.player.GetEqualizer()
+
+		r, g, b := gomu.colors.accent.RGB()
+		accent := padHex(r, g, b)
+
+		var text strings.Builder
+		fmt.Fprintf(&text, "\n preset: [#%s]%s[-]\n\n", accent, currentEqualizerPreset())
+
+		for i, freq := range player.EqualizerBands {
+
+			label := fmt.Sprintf("%.0f", freq)
+			if freq >= 1000 {
+				label = fmt.Sprintf("%.0fk", freq/1000)
+			}
+
+			progress := int(gains[i] - player.MinEqualizerGain)
+			slider := progresStr(progress, maxLength, maxLength, "█", "-")
+
+			line := fmt.Sprintf(" %5sHz |%s| %+5.1f dB", label, slider, gains[i])
+			if i == selected {
+				line = fmt.Sprintf("[#%s]%s[-]", accent, line)
+			}
+
+			fmt.Fprintln(&text, line)
+		}
+
+		fmt.Fprint(&text, "\n j/k select  h/l adjust  0 reset  p presets")
+		textView.SetText(text.String())
+	}
+
+	adjust := func(delta float64) {
+
+		gains := gomu.player.GetEqualizer()
+
+		if delta == 0 {
+			gains[selected] = 0
+		} else {
+			gains[selected] = math.Round(gains[selected] + delta)
+		}
+
+		err := gomu.player.SetEqualizer(gains)
+		if err != nil {
+			errorPopup(err)
+		}
+		draw()
+	}
+
+	textView.SetInputCapture(func(e *tcell.EventKey) *tcell.EventKey {
+
+		switch e.Rune() {
+		case 'j':
+			selected = (selected + 1) % len(player.EqualizerBands)
+			draw()
+		case 'k':
+			selected = (selected - 1 + len(player.EqualizerBands)) %
+				len(player.EqualizerBands)
+			draw()
+		case 'l':
+			adjust(1)
+		case 'h':
+			adjust(-1)
+		case '0':
+			adjust(0)
+		case 'p':
+
+			presets := getEqualizerPresets()
+			names := make([]string, 0, len(presets))
+			for name := range presets {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			searchPopup("Presets", names, func(selected string) {
+				err := setEqualizer(equalizerSettings{Preset: selected})
+				if err != nil {
+					errorPopup(err)
+				}
+				draw()
+			})
+		}
+
+		switch e.Key() {
+		case tcell.KeyEsc:
+			gomu.pages.RemovePage(popupID)
+			gomu.popups.pop()
+
+			err := saveEqualizer()
+			if err != nil {
+				errorPopup(err)
+			}
+		}
+
+		return nil
+	})
+
+	textView.SetBackgroundColor(gomu.colors.popup)
+	textView.SetBorder(true).SetTitle(" Equalizer ")
+
+	draw()
+
+	if gomu.playingBar.albumPhoto != nil {
+		gomu.playingBar.albumPhoto.Clear()
+	}
+
+	gomu.pages.AddPage(popupID, center(textView, 55, 19), true, true)
+	gomu.popups.push(textView)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+func TestToFloats(t *testing.T) {
+
+	gains, err := toFloats([]interface{}{int64(1), 2.5, int64(-3)})
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1, 2.5, -3}, gains)
+
+	_, err = toFloats([]interface{}{"loud"})
+	assert.Error(t, err)
+
+	_, err = toFloats("loud")
+	assert.Error(t, err)
+}
+
+func prepareEqualizerTest(t *testing.T) {
+
+	gomu = newGomu()
+	gomu.player = player.New(0)
+
+	err := execConfig(expandFilePath(testConfigPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetEqualizer(t *testing.T) {
+
+	prepareEqualizerTest(t)
+
+	presets := getEqualizerPresets()
+	assert.Contains(t, presets, "flat")
+	assert.Contains(t, presets, "rock")
+
+	settings, err := getEqualizerConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "flat", settings.Preset)
+
+	err = setEqualizer(equalizerSettings{Preset: "rock"})
+	assert.NoError(t, err)
+	assert.Equal(t, presets["rock"], gomu.player.GetEqualizer())
+	assert.Equal(t, "rock", currentEqualizerPreset())
+
+	bands := []float64{1, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	err = setEqualizer(equalizerSettings{Bands: bands})
+	assert.NoError(t, err)
+	assert.Equal(t, customPreset, currentEqualizerPreset())
+
+	err = setEqualizer(equalizerSettings{Preset: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestSaveEqualizer(t *testing.T) {
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	prepareEqualizerTest(t)
+
+	// nothing saved yet, uses config file
+	err := loadEqualizer()
+	assert.NoError(t, err)
+	assert.Equal(t, "flat", currentEqualizerPreset())
+
+	bands := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	err = gomu.player.SetEqualizer(bands)
+	assert.NoError(t, err)
+
+	err = saveEqualizer()
+	assert.NoError(t, err)
+
+	prepareEqualizerTest(t)
+
+	err = loadEqualizer()
+	assert.NoError(t, err)
+	assert.Equal(t, bands, gomu.player.GetEqualizer())
+}
@@ -0,0 +1,241 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ztrue/tracerr"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// Handles the events of the player one at a time until the player is closed.
+// The events are handled on the UI goroutine since they update the panels.
+func handlePlayerEvents(events <-chan player.Event) {
+	for e := range events {
+		e := e
+		gomu.app.QueueUpdateDraw(func() {
+			handlePlayerEvent(e)
+		})
+	}
+}
+
+// Updates the application to reflect the change of the player and runs the
+// hooks of the event, it must be called from the UI goroutine
+func handlePlayerEvent(e player.Event) {
+
+	switch e.Type {
+	case player.EventStarted:
+		onSongStart(e.Audio)
+		gomu.hook.RunHooks("new_song")
+
+	case player.EventChanged:
+		onSongChange(e.Prev, e.Audio)
+
+	case player.EventFinished:
+		rememberPosition(e.Audio, e.Position)
+		addHistory(e.Audio)
+		onSongFinish(e.Audio)
+
+	case player.EventStopped:
+		rememberPosition(e.Audio, e.Position)
+		addHistory(e.Audio)
+		gomu.playingBar.subtitles = nil
+		gomu.playingBar.subtitle = nil
+		gomu.playingBar.setDefault()
+
+	case player.EventSkipped:
+		gomu.hook.RunHooks("skip")
+
+	case player.EventPaused:
+		gomu.hook.RunHooks("pause")
+
+	case player.EventResumed:
+		gomu.hook.RunHooks("play")
+
+	case player.EventTitle:
+		onStreamTitle(e.Audio, e.Title)
+
+	case player.EventError:
+		errorPopup(e.Err)
+		gomu.playingBar.setDefault()
+	}
+
+	// the next song depends on the current song when repeating
+	gomu.queue.updateNextSong()
+}
+
+// Gets length of the song from its tag, the song is decoded if the tag has no
+// length
+func songLength(audio player.Audio) (time.Duration, error) {
+
+	// track of cue sheet knows its length
+	if audioFile, ok := audio.(*player.AudioFile); ok && isCueTrack(audioFile) {
+		return audioFile.Len(), nil
+	}
+
+	songPath := audio.Path()
+	duration, err := getTagLength(songPath)
+	if err != nil || (duration == 0 && !player.IsStream(songPath)) {
+		duration, err = player.GetLength(songPath)
+		if err != nil {
+			return 0, tracerr.Wrap(err)
+		}
+	}
+
+	return duration, nil
+}
+
+// Shows the song which has started playing
+func onSongStart(audio player.Audio) {
+
+	// songs added by play next go right after the new song
+	gomu.queue.nextIndex = 0
+
+	duration, err := songLength(audio)
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	// continues from where the song was left off
+	if pos, ok := gomu.positions.get(positionKey(audio)); ok {
+		err := gomu.player.Seek(int(pos.Seconds()))
+		if err != nil {
+			logError(err)
+		}
+	}
+
+	audioFile, ok := audio.(*player.AudioFile)
+	if !ok {
+		return
+	}
+
+	gomu.playingBar.newProgress(audioFile, int(duration.Seconds()))
+
+	name := audio.Name()
+	var description string
+
+	if len(gomu.playingBar.subtitles) == 0 {
+		description = name
+	} else {
+		lang := gomu.playingBar.subtitle.LangExt
+
+		description = fmt.Sprintf("%s \n\n %s lyric loaded", name, lang)
+	}
+
+	defaultTimedPopup(" Now Playing ", description)
+
+	go func() {
+		err := gomu.playingBar.run()
+		if err != nil {
+			logError(err)
+		}
+	}()
+}
+
+// Shows the song which has started playing on live stream
+func onStreamTitle(audio player.Audio, title string) {
+
+	description := fmt.Sprintf("%s - %s", audio.Name(), title)
+
+	gomu.playingBar.setSongTitle(description)
+	defaultTimedPopup(" Now Playing ", description)
+	gomu.hook.RunHooks("new_song")
+}
+
+// Plays the next song in the queue when the song finishes
+func onSongFinish(audio player.Audio) {
+
+	gomu.playingBar.subtitles = nil
+	gomu.playingBar.subtitle = nil
+
+	if audioFile, ok := audio.(*player.AudioFile); ok && gomu.queue.repeat == repeatAll {
+		repeatSong(audioFile)
+	}
+
+	if gomu.queue.stopAfterCurrent {
+		gomu.queue.toggleStopAfterCurrent()
+		gomu.playingBar.setDefault()
+		return
+	}
+
+	if len(gomu.queue.items) > 0 {
+		err := gomu.queue.playQueue()
+		if err != nil {
+			logError(err)
+		}
+	} else {
+		gomu.playingBar.setDefault()
+	}
+}
+
+// Puts the played song back to the end of the queue to repeat all songs. It is
+// recorded like the other queue edits, so undoing an earlier edit does not
+// lose it.
+func repeatSong(audioFile *player.AudioFile) {
+	gomu.queue.record("repeat", func() {
+		_, err := gomu.queue.enqueue(audioFile)
+		if err != nil {
+			logError(err)
+		}
+	})
+}
+
+// Remembers where the song was left off, see Positions
+func rememberPosition(audio player.Audio, pos time.Duration) {
+
+	if audio == nil || player.IsStream(audio.Path()) {
+		return
+	}
+
+	length, err := songLength(audio)
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	err = gomu.positions.remember(positionKey(audio), pos, length)
+	if err != nil {
+		logError(err)
+	}
+}
+
+// Adds the song which is no longer played to the history, see playPrev
+func addHistory(audio player.Audio) {
+	if audioFile, ok := audio.(*player.AudioFile); ok {
+		gomu.history.push(audioFile)
+	}
+}
+
+// Updates the queue when the next song takes over the finished song
+func onSongChange(prev, next player.Audio) {
+
+	// the previous song is played to the end
+	err := gomu.positions.forget(positionKey(prev))
+	if err != nil {
+		logError(err)
+	}
+
+	// the same song is played again by repeat one or as the only song left in
+	// the loop, so it is not in the queue
+	repeated := prev == next
+
+	if !repeated {
+		addHistory(prev)
+	}
+
+	prevFile, ok := prev.(*player.AudioFile)
+	if ok && gomu.queue.repeat == repeatAll && !repeated {
+		repeatSong(prevFile)
+	}
+
+	// the next song is already playing, only remove it from the queue. Stop
+	// after current drops the next song before it starts, so when set later
+	// it stops after this song.
+	if nextFile, ok := next.(*player.AudioFile); ok && !repeated {
+		gomu.queue.remove(nextFile)
+	}
+}
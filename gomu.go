@@ -25,21 +25,27 @@ type Gomu struct {
 	colors     *Colors
 	command    Command
 	// popups is used to manage focus between popups and panels
-	popups    Stack
-	prevPanel Panel
-	panels    []Panel
-	args      Args
-	anko      *anko.Anko
-	hook      *hook.EventHook
+	popups     Stack
+	prevPanel  Panel
+	panels     []Panel
+	args       Args
+	anko       *anko.Anko
+	hook       *hook.EventHook
+	sleepTimer *SleepTimer
+	positions  *Positions
+	history    *History
 }
 
 // Creates new instance of gomu with default values
 func newGomu() *Gomu {
 
 	gomu := &Gomu{
-		command: newCommand(),
-		anko:    anko.NewAnko(),
-		hook:    hook.NewEventHook(),
+		command:    newCommand(),
+		anko:       anko.NewAnko(),
+		hook:       hook.NewEventHook(),
+		sleepTimer: newSleepTimer(),
+		positions:  newPositions(positionsPath()),
+		history:    newHistory(historySize),
 	}
 
 	return gomu
@@ -139,14 +145,27 @@ func (g *Gomu) setUnfocusPanel(panel Panel) {
 // Quit the application and do the neccessary clean up
 func (g *Gomu) quit(args Args) error {
 
-	if !*args.empty {
-		err := gomu.queue.saveQueue()
+	if current := gomu.player.GetCurrentSong(); current != nil &&
+		gomu.player.State() != player.Stopped {
+		err := gomu.positions.remember(
+			positionKey(current), gomu.player.GetPosition(), gomu.player.GetLength())
 		if err != nil {
-			return tracerr.Wrap(err)
+			logError(err)
 		}
 	}
 
+	err := saveSession()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
 	gomu.app.Stop()
 
+	// finishes writing the output file
+	err = gomu.player.Close()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
 	return nil
 }
@@ -0,0 +1,133 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ztrue/tracerr"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// number of played songs kept in the history
+const historySize = 100
+
+// going to the previous song restarts the current song instead if it has been
+// played longer than this
+const prevRestart = 3 * time.Second
+
+// History is the stack of played songs, the oldest song is dropped once the
+// stack is full
+type History struct {
+	mu    sync.Mutex
+	songs []*player.AudioFile
+	size  int
+	// song which is not pushed the next time it stops, see skipNext
+	skip *player.AudioFile
+}
+
+func newHistory(size int) *History {
+	return &History{size: size}
+}
+
+// Push the played song to the top of the stack
+func (h *History) push(audioFile *player.AudioFile) {
+
+	if audioFile == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if audioFile == h.skip {
+		h.skip = nil
+		return
+	}
+
+	h.songs = append(h.songs, audioFile)
+
+	if len(h.songs) > h.size {
+		// avoid memory leak
+		h.songs[0] = nil
+		h.songs = h.songs[len(h.songs)-h.size:]
+	}
+}
+
+// Keeps the song out of the history when it stops next time, the song is
+// replaced without being done with, eg. by playPrev. nil clears it.
+func (h *History) skipNext(audioFile *player.AudioFile) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.skip = audioFile
+}
+
+// Remove the last played song from the stack, nil if the stack is empty
+func (h *History) pop() *player.AudioFile {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.songs) == 0 {
+		return nil
+	}
+
+	last := h.songs[len(h.songs)-1]
+	h.songs[len(h.songs)-1] = nil
+	h.songs = h.songs[:len(h.songs)-1]
+
+	return last
+}
+
+func (h *History) len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.songs)
+}
+
+// Plays the previous song, the current song is put back to the front of the
+// queue. The current song is restarted instead if it has been played for a
+// while or there is no previous song. The current song is replaced by Run like
+// any other song, so it is stopped even if it is paused.
+func playPrev() error {
+
+	current, _ := gomu.player.GetCurrentSong().(*player.AudioFile)
+	isPlaying := gomu.player.State() != player.Stopped
+	// live stream cannot be restarted, the previous song is played instead
+	canRestart := current != nil && !player.IsStream(current.Path())
+
+	if isPlaying && canRestart &&
+		(gomu.player.GetPosition() > prevRestart || gomu.history.len() == 0) {
+		err := gomu.player.Seek(0)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+		gomu.playingBar.setProgress(0)
+		return nil
+	}
+
+	prev := gomu.history.pop()
+	if prev == nil {
+		return nil
+	}
+
+	// the current song is stopped by Run, it goes back to the queue instead
+	if isPlaying {
+		gomu.history.skipNext(current)
+	}
+
+	err := gomu.player.Run(prev)
+	if err != nil {
+		gomu.history.skipNext(nil)
+		gomu.history.push(prev)
+		return tracerr.Wrap(err)
+	}
+
+	if isPlaying && current != nil {
+		gomu.queue.pushFront(current)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+func TestHistory(t *testing.T) {
+
+	h := newHistory(2)
+	assert.Nil(t, h.pop())
+
+	songs := make([]*player.AudioFile, 3)
+	for i := range songs {
+		songs[i] = new(player.AudioFile)
+		h.push(songs[i])
+	}
+
+	h.push(nil)
+
+	// the oldest song is dropped
+	assert.Equal(t, 2, h.len())
+	assert.Equal(t, songs[2], h.pop())
+	assert.Equal(t, songs[1], h.pop())
+	assert.Nil(t, h.pop())
+}
+
+func TestHistorySkipNext(t *testing.T) {
+
+	h := newHistory(2)
+	song := new(player.AudioFile)
+
+	h.skipNext(song)
+	h.push(song)
+	assert.Equal(t, 0, h.len())
+
+	// only skipped once
+	h.push(song)
+	assert.Equal(t, song, h.pop())
+}
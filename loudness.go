@@ -0,0 +1,255 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rivo/tview"
+	spin "github.com/tj/go-spin"
+	"github.com/ztrue/tracerr"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// collectAlbums walks the directory and groups mp3 files by the directory
+// they are in. Each group is treated as an album when computing album gain.
+func collectAlbums(dir string) ([][]string, error) {
+
+	albums := make(map[string][]string)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		// only mp3 carries id3v2 tag
+		if format, _ := player.FormatOf(path); format == "mp3" {
+			parent := filepath.Dir(path)
+			albums[parent] = append(albums[parent], path)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	dirs := make([]string, 0, len(albums))
+	for d := range albums {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+
+	result := make([][]string, 0, len(dirs))
+	for _, d := range dirs {
+		result = append(result, albums[d])
+	}
+
+	return result, nil
+}
+
+// scanLoudness measures every file of the albums in background workers and
+// writes the ReplayGain tags. Progress is called after every measured file.
+// It returns the number of files that failed.
+func scanLoudness(albums [][]string, progress func(done, total int)) int {
+
+	type job struct {
+		album, track int
+	}
+
+	total := 0
+	meters := make([][]*player.LoudnessMeter, len(albums))
+	for i, album := range albums {
+		meters[i] = make([]*player.LoudnessMeter, len(album))
+		total += len(album)
+	}
+
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	var done, failed int32
+
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				m, err := player.MeasureLoudness(albums[j.album][j.track])
+				if err != nil {
+					logError(err)
+					atomic.AddInt32(&failed, 1)
+				}
+				meters[j.album][j.track] = m
+				progress(int(atomic.AddInt32(&done, 1)), total)
+			}
+		}()
+	}
+
+	for i, album := range albums {
+		for j := range album {
+			jobs <- job{i, j}
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	for i, album := range albums {
+
+		var measured []*player.LoudnessMeter
+		for _, m := range meters[i] {
+			if m != nil {
+				measured = append(measured, m)
+			}
+		}
+
+		if len(measured) == 0 {
+			continue
+		}
+
+		albumGain := player.ReplayGainOf(player.IntegratedLoudness(measured...))
+		var albumPeak float64
+		for _, m := range measured {
+			albumPeak = math.Max(albumPeak, m.TruePeak())
+		}
+
+		for j, songPath := range album {
+
+			m := meters[i][j]
+			if m == nil {
+				continue
+			}
+
+			rg := player.ReplayGain{
+				TrackGain: player.ReplayGainOf(m.Integrated()),
+				TrackPeak: m.TruePeak(),
+				AlbumGain: albumGain,
+				AlbumPeak: albumPeak,
+			}
+
+			err := embedReplayGain(songPath, rg)
+			if err != nil {
+				logError(err)
+				failed++
+			}
+		}
+	}
+
+	return int(failed)
+}
+
+// scanLoudnessDir scans the directory and prints the progress to stdout.
+func scanLoudnessDir(dir string) error {
+
+	albums, err := collectAlbums(dir)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	var mu sync.Mutex
+	failed := scanLoudness(albums, func(done, total int) {
+		mu.Lock()
+		fmt.Printf("\rscanning %d/%d", done, total)
+		mu.Unlock()
+	})
+
+	fmt.Println()
+
+	if failed > 0 {
+		return tracerr.Errorf("unable to scan %d files, see gomu.log", failed)
+	}
+
+	return nil
+}
+
+// scanLoudness scans the playlist node and shows the progress on the title
+// of the playlist panel.
+func (p *Playlist) scanLoudness(node *tview.TreeNode) error {
+
+	if !atomic.CompareAndSwapInt32(&p.scanTotal, 0, -1) {
+		return tracerr.New("loudness scan is already running")
+	}
+	defer atomic.StoreInt32(&p.scanTotal, 0)
+
+	audioFile := node.GetReference().(*player.AudioFile)
+
+	albums, err := collectAlbums(audioFile.Path())
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	total := 0
+	for _, album := range albums {
+		total += len(album)
+	}
+
+	if total == 0 {
+		return tracerr.New("no mp3 file to scan")
+	}
+
+	atomic.StoreInt32(&p.scanned, 0)
+	atomic.StoreInt32(&p.scanTotal, int32(total))
+
+	done := make(chan struct{})
+	go p.updateScanTitle(done)
+
+	failed := scanLoudness(albums, func(scanned, _ int) {
+		atomic.StoreInt32(&p.scanned, int32(scanned))
+	})
+
+	close(done)
+
+	if failed > 0 {
+		return tracerr.Errorf("unable to scan %d files", failed)
+	}
+
+	defaultTimedPopup(" Loudness ", fmt.Sprintf(
+		"Finished scanning\n%s", audioFile.Name()))
+
+	return nil
+}
+
+// updateScanTitle creates a spinning motion on the title of the playlist
+// panel until done is closed. Downloads take precedence over the title.
+func (p *Playlist) updateScanTitle(done <-chan struct{}) {
+
+	s := spin.New()
+
+	for {
+		select {
+		case <-done:
+			if p.download == 0 {
+				p.SetTitle(p.defaultTitle)
+				gomu.app.Draw()
+			}
+			return
+		case <-time.After(time.Millisecond * 100):
+
+			if p.download > 0 {
+				continue
+			}
+
+			r, g, b := gomu.colors.accent.RGB()
+			hexColor := padHex(r, g, b)
+
+			title := fmt.Sprintf("─ Playlist ──┤ scanning %d/%d [green]%s[#%s] ├",
+				atomic.LoadInt32(&p.scanned), atomic.LoadInt32(&p.scanTotal),
+				s.Next(), hexColor)
+			p.SetTitle(title)
+			gomu.app.Draw()
+		}
+	}
+}
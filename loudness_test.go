@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+func TestScanLoudness(t *testing.T) {
+
+	dir := t.TempDir()
+	albumDir := filepath.Join(dir, "album")
+
+	err := os.Mkdir(albumDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	songs := []string{
+		filepath.Join(dir, "single.mp3"),
+		filepath.Join(albumDir, "one.mp3"),
+		filepath.Join(albumDir, "two.mp3"),
+	}
+
+	data, err := ioutil.ReadFile("./test/rap/audio_test.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, song := range songs {
+		err := ioutil.WriteFile(song, data, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// non audio files are skipped
+	err = ioutil.WriteFile(filepath.Join(albumDir, "cover.txt"), []byte("cover"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	albums, err := collectAlbums(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, [][]string{songs[:1], songs[1:]}, albums)
+
+	var progress int
+	failed := scanLoudness(albums, func(done, total int) {
+		progress++
+		assert.Equal(t, len(songs), total)
+	})
+
+	assert.Equal(t, 0, failed)
+	assert.Equal(t, len(songs), progress)
+
+	for _, song := range songs {
+		rg, err := player.ReadReplayGain(song)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.True(t, rg.HasTrack)
+		assert.True(t, rg.HasAlbum)
+		assert.True(t, rg.TrackPeak > 0)
+		// identical songs have the same track and album gain
+		assert.InDelta(t, rg.TrackGain, rg.AlbumGain, 0.01)
+	}
+}
@@ -20,8 +20,11 @@ type AudioFile struct {
 	path        string
 	isAudioFile bool
 	length      time.Duration
-	node        *tview.TreeNode
-	parent      *tview.TreeNode
+	// part of the file being played, end is zero for the end of the file
+	start, end time.Duration
+	bounded    bool
+	node       *tview.TreeNode
+	parent     *tview.TreeNode
 }
 
 // Name return the name of AudioFile
@@ -67,6 +70,19 @@ func (a *AudioFile) SetLen(length time.Duration) {
 	a.length = length
 }
 
+// Bounds return the part of the file played by AudioFile, ok is false when the
+// whole file is played
+func (a *AudioFile) Bounds() (start, end time.Duration, ok bool) {
+	return a.start, a.end, a.bounded
+}
+
+// SetBounds set the part of the file played by AudioFile, zero end is the end
+// of the file
+func (a *AudioFile) SetBounds(start, end time.Duration) {
+	a.start, a.end = start, end
+	a.bounded = true
+}
+
 // Parent return the parent directory of AudioFile
 func (a *AudioFile) Parent() *AudioFile {
 	if a.parent == nil {
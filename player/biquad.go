@@ -0,0 +1,26 @@
+// Copyright (C) 2020  Raziman
+
+package player
+
+// biquad is a second order IIR filter in transposed direct form II. The
+// coefficients are normalized so that a0 equals 1.
+type biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+	// filter state per channel
+	z1, z2 [2]float64
+}
+
+// process filters one sample of the channel.
+func (f *biquad) process(ch int, x float64) float64 {
+	y := f.b0*x + f.z1[ch]
+	f.z1[ch] = f.b1*x - f.a1*y + f.z2[ch]
+	f.z2[ch] = f.b2*x - f.a2*y
+	return y
+}
+
+// reset clears the filter state.
+func (f *biquad) reset() {
+	f.z1 = [2]float64{}
+	f.z2 = [2]float64{}
+}
@@ -0,0 +1,243 @@
+// Copyright (C) 2020  Raziman
+
+package player
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tramhao/id3v2"
+	"github.com/ztrue/tracerr"
+)
+
+// Chapter is a section of the song described by ID3 CHAP frame.
+type Chapter struct {
+	ID    string
+	Title string
+	Start time.Duration
+	End   time.Duration
+}
+
+// tableOfContents is ID3 CTOC frame which lists the chapters in order.
+type tableOfContents struct {
+	id       string
+	topLevel bool
+	ordered  bool
+	children []string
+}
+
+var errChapterFrame = errors.New("invalid chapter frame")
+
+// LoadChapters returns the chapters of the song in the order they are played,
+// it returns nil if the song has none. Only mp3 carries id3v2 tag.
+func (a *AudioFile) LoadChapters() ([]Chapter, error) {
+
+	if IsStream(a.path) {
+		return nil, nil
+	}
+
+	if format, _ := FormatOf(a.path); format != "mp3" {
+		return nil, nil
+	}
+
+	tag, err := id3v2.Open(a.path, id3v2.Options{
+		Parse:       true,
+		ParseFrames: []string{"CHAP", "CTOC"},
+	})
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer tag.Close()
+
+	return chaptersOf(tag)
+}
+
+// chaptersOf returns the chapters in the order of the top level table of
+// contents, the chapters are sorted by their start time otherwise.
+func chaptersOf(tag *id3v2.Tag) ([]Chapter, error) {
+
+	var chapters []Chapter
+
+	for _, f := range tag.GetFrames("CHAP") {
+		frame, ok := f.(id3v2.UnknownFrame)
+		if !ok {
+			continue
+		}
+		chapter, err := parseChapter(frame.Body, tag.Version())
+		if err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+		chapters = append(chapters, chapter)
+	}
+
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return chapters[i].Start < chapters[j].Start
+	})
+
+	for _, f := range tag.GetFrames("CTOC") {
+		frame, ok := f.(id3v2.UnknownFrame)
+		if !ok {
+			continue
+		}
+		toc, err := parseTableOfContents(frame.Body)
+		if err != nil {
+			return nil, tracerr.Wrap(err)
+		}
+		if toc.topLevel && toc.ordered {
+			return orderChapters(chapters, toc.children), nil
+		}
+	}
+
+	return chapters, nil
+}
+
+// orderChapters puts the chapters in the given order, chapters which are not
+// listed come last.
+func orderChapters(chapters []Chapter, ids []string) []Chapter {
+
+	index := make(map[string]int)
+	for i, id := range ids {
+		if _, ok := index[id]; !ok {
+			index[id] = i
+		}
+	}
+
+	rank := func(c Chapter) int {
+		if i, ok := index[c.ID]; ok {
+			return i
+		}
+		return len(ids)
+	}
+
+	ordered := append([]Chapter(nil), chapters...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return rank(ordered[i]) < rank(ordered[j])
+	})
+
+	return ordered
+}
+
+// parseChapter parses the body of CHAP frame. The body is made of the element
+// id, start and end time in milliseconds, start and end byte offset and
+// optional frames describing the chapter.
+func parseChapter(body []byte, version byte) (Chapter, error) {
+
+	id, rest, err := cutString(body)
+	if err != nil {
+		return Chapter{}, err
+	}
+
+	if len(rest) < 16 {
+		return Chapter{}, errChapterFrame
+	}
+
+	chapter := Chapter{
+		ID:    id,
+		Start: time.Duration(binary.BigEndian.Uint32(rest[0:4])) * time.Millisecond,
+		End:   time.Duration(binary.BigEndian.Uint32(rest[4:8])) * time.Millisecond,
+		Title: id,
+	}
+
+	sub, err := parseSubFrames(rest[16:], version)
+	if err != nil {
+		return Chapter{}, err
+	}
+
+	if title := sub.Title(); title != "" {
+		chapter.Title = title
+	}
+
+	return chapter, nil
+}
+
+// parseTableOfContents parses the body of CTOC frame. The body is made of the
+// element id, flags, number of children followed by their element id and
+// optional frames describing the table.
+func parseTableOfContents(body []byte) (tableOfContents, error) {
+
+	id, rest, err := cutString(body)
+	if err != nil {
+		return tableOfContents{}, err
+	}
+
+	if len(rest) < 2 {
+		return tableOfContents{}, errChapterFrame
+	}
+
+	toc := tableOfContents{
+		id:       id,
+		topLevel: rest[0]&2 != 0,
+		ordered:  rest[0]&1 != 0,
+	}
+
+	count := int(rest[1])
+	rest = rest[2:]
+
+	for i := 0; i < count; i++ {
+		var child string
+		child, rest, err = cutString(rest)
+		if err != nil {
+			return tableOfContents{}, err
+		}
+		toc.children = append(toc.children, child)
+	}
+
+	return toc, nil
+}
+
+// parseSubFrames parses the frames embedded in chapter frame by wrapping them
+// in a tag of the same version.
+func parseSubFrames(frames []byte, version byte) (*id3v2.Tag, error) {
+
+	if len(frames) == 0 {
+		return id3v2.NewEmptyTag(), nil
+	}
+
+	size := len(frames)
+	header := []byte{
+		'I', 'D', '3', version, 0, 0,
+		// tag size is synchsafe integer in every version
+		byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f),
+		byte(size >> 7 & 0x7f), byte(size & 0x7f),
+	}
+
+	tag, err := id3v2.ParseReader(
+		bytes.NewReader(append(header, frames...)),
+		id3v2.Options{Parse: true},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errChapterFrame, err)
+	}
+
+	return tag, nil
+}
+
+// cutString returns the null terminated string at the start of b and the rest
+// of b.
+func cutString(b []byte) (string, []byte, error) {
+
+	i := bytes.IndexByte(b, 0)
+	if i < 0 {
+		return "", nil, errChapterFrame
+	}
+
+	return string(b[:i]), b[i+1:], nil
+}
+
+// ChapterAt returns the index of the chapter being played at the position, it
+// returns -1 if the position is before the first chapter.
+func ChapterAt(chapters []Chapter, pos time.Duration) int {
+
+	current := -1
+	for i, c := range chapters {
+		if c.Start <= pos && (current < 0 || c.Start >= chapters[current].Start) {
+			current = i
+		}
+	}
+
+	return current
+}
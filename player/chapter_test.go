@@ -0,0 +1,140 @@
+package player
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tramhao/id3v2"
+)
+
+// returns the body of CHAP frame with TIT2 frame as the title
+func chapterBody(id string, start, end time.Duration, title string) []byte {
+
+	body := append([]byte(id), 0)
+
+	times := make([]byte, 16)
+	binary.BigEndian.PutUint32(times[0:], uint32(start.Milliseconds()))
+	binary.BigEndian.PutUint32(times[4:], uint32(end.Milliseconds()))
+	binary.BigEndian.PutUint32(times[8:], 0xffffffff)
+	binary.BigEndian.PutUint32(times[12:], 0xffffffff)
+	body = append(body, times...)
+
+	if title == "" {
+		return body
+	}
+
+	// utf-8 encoded text, size is small enough to be synchsafe
+	text := append([]byte{3}, title...)
+	body = append(body, 'T', 'I', 'T', '2', 0, 0, 0, byte(len(text)), 0, 0)
+
+	return append(body, text...)
+}
+
+// returns the body of CTOC frame listing the children
+func tocBody(id string, flags byte, children ...string) []byte {
+
+	body := append([]byte(id), 0, flags, byte(len(children)))
+	for _, child := range children {
+		body = append(body, child...)
+		body = append(body, 0)
+	}
+
+	return body
+}
+
+func TestParseChapter(t *testing.T) {
+
+	for _, version := range []byte{3, 4} {
+		body := chapterBody("ch1", 1500*time.Millisecond, time.Minute, "Intro")
+
+		chapter, err := parseChapter(body, version)
+		assert.NoError(t, err)
+		assert.Equal(t, Chapter{
+			ID:    "ch1",
+			Title: "Intro",
+			Start: 1500 * time.Millisecond,
+			End:   time.Minute,
+		}, chapter)
+	}
+
+	// element id is used when there is no title
+	chapter, err := parseChapter(chapterBody("ch2", 0, time.Second, ""), 4)
+	assert.NoError(t, err)
+	assert.Equal(t, "ch2", chapter.Title)
+
+	_, err = parseChapter([]byte("ch1"), 4)
+	assert.Error(t, err)
+
+	_, err = parseChapter([]byte("ch1\x00\x00\x00"), 4)
+	assert.Error(t, err)
+}
+
+func TestChaptersOf(t *testing.T) {
+
+	tag := id3v2.NewEmptyTag()
+	tag.AddFrame("CHAP", id3v2.UnknownFrame{
+		Body: chapterBody("b", time.Minute, 2*time.Minute, "Second"),
+	})
+	tag.AddFrame("CHAP", id3v2.UnknownFrame{
+		Body: chapterBody("a", 0, time.Minute, "First"),
+	})
+
+	chapters, err := chaptersOf(tag)
+	assert.NoError(t, err)
+	assert.Equal(t, "First", chapters[0].Title)
+	assert.Equal(t, "Second", chapters[1].Title)
+
+	// ordered top level table of contents takes precedence
+	tag.AddFrame("CTOC", id3v2.UnknownFrame{Body: tocBody("toc", 3, "b", "a")})
+
+	chapters, err = chaptersOf(tag)
+	assert.NoError(t, err)
+	assert.Equal(t, "Second", chapters[0].Title)
+	assert.Equal(t, "First", chapters[1].Title)
+}
+
+func TestLoadChapters(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "chapters.mp3")
+	copyFile(t, "../test/rap/audio_test.mp3", path)
+
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag.AddFrame("CHAP", id3v2.UnknownFrame{
+		Body: chapterBody("ch1", 0, 10*time.Second, "Intro"),
+	})
+	tag.AddFrame("CHAP", id3v2.UnknownFrame{
+		Body: chapterBody("ch2", 10*time.Second, 20*time.Second, "Verse"),
+	})
+	assert.NoError(t, tag.Save())
+	tag.Close()
+
+	audio := &AudioFile{path: path, isAudioFile: true}
+	chapters, err := audio.LoadChapters()
+	assert.NoError(t, err)
+	assert.Len(t, chapters, 2)
+	assert.Equal(t, "Verse", chapters[1].Title)
+
+	// other than mp3 has no chapters
+	chapters, err = (&AudioFile{path: "song.flac"}).LoadChapters()
+	assert.NoError(t, err)
+	assert.Nil(t, chapters)
+}
+
+func TestChapterAt(t *testing.T) {
+
+	chapters := []Chapter{
+		{Start: 5 * time.Second},
+		{Start: time.Minute},
+	}
+
+	assert.Equal(t, -1, ChapterAt(chapters, time.Second))
+	assert.Equal(t, 0, ChapterAt(chapters, 5*time.Second))
+	assert.Equal(t, 1, ChapterAt(chapters, time.Hour))
+	assert.Equal(t, -1, ChapterAt(nil, time.Hour))
+}
@@ -0,0 +1,222 @@
+// Copyright (C) 2020  Raziman
+
+package player
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/flac"
+	"github.com/faiface/beep/mp3"
+	"github.com/faiface/beep/vorbis"
+	"github.com/faiface/beep/wav"
+	"github.com/ztrue/tracerr"
+)
+
+// DecodeFunc decodes audio data from rc. The returned stream owns rc and
+// closes it when the stream is closed.
+type DecodeFunc func(rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error)
+
+// Decoder describes an audio format that the player is able to play.
+type Decoder struct {
+	// Name is the short name of the format, eg. "mp3"
+	Name string
+	// Extensions are the file extensions of the format including the dot
+	Extensions []string
+	// Sniff reports whether the header of a file belongs to this format
+	Sniff  func(header []byte) bool
+	Decode DecodeFunc
+	// Tags reads the tags of the format, nil if the format has no tags
+	Tags TagsFunc
+}
+
+// header size needed to sniff the content of audio files
+const sniffLen = 64
+
+var decoders []Decoder
+
+func init() {
+	RegisterDecoder(Decoder{
+		Name:       "mp3",
+		Extensions: []string{".mp3"},
+		Sniff:      sniffMP3,
+		Decode:     mp3.Decode,
+		Tags:       id3Tags,
+	})
+	RegisterDecoder(Decoder{
+		Name:       "flac",
+		Extensions: []string{".flac"},
+		Sniff: func(header []byte) bool {
+			return bytes.HasPrefix(header, []byte("fLaC"))
+		},
+		Decode: func(rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+			return flac.Decode(rc)
+		},
+		Tags: flacTags,
+	})
+	RegisterDecoder(Decoder{
+		Name:       "vorbis",
+		Extensions: []string{".ogg", ".oga"},
+		Sniff: func(header []byte) bool {
+			// the first ogg page of a vorbis stream carries the vorbis
+			// identification header
+			return bytes.HasPrefix(header, []byte("OggS")) &&
+				bytes.Contains(header, []byte("\x01vorbis"))
+		},
+		Decode: vorbis.Decode,
+		Tags:   oggTags,
+	})
+	RegisterDecoder(Decoder{
+		Name:       "wav",
+		Extensions: []string{".wav", ".wave"},
+		Sniff: func(header []byte) bool {
+			return len(header) >= 12 &&
+				bytes.Equal(header[:4], []byte("RIFF")) &&
+				bytes.Equal(header[8:12], []byte("WAVE"))
+		},
+		Decode: func(rc io.ReadCloser) (beep.StreamSeekCloser, beep.Format, error) {
+			return wav.Decode(rc)
+		},
+		Tags: wavTags,
+	})
+}
+
+// RegisterDecoder adds decoder to the registry. Decoders registered later take
+// precedence over the earlier ones.
+func RegisterDecoder(d Decoder) {
+	decoders = append([]Decoder{d}, decoders...)
+}
+
+// sniffMP3 checks for ID3v2 tag or a mpeg audio frame sync.
+func sniffMP3(header []byte) bool {
+	if bytes.HasPrefix(header, []byte("ID3")) {
+		return true
+	}
+	return len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0
+}
+
+// findDecoder picks decoder by the content of the file first, and falls back
+// to the file extension.
+func findDecoder(audioPath string, header []byte) (Decoder, bool) {
+
+	sniff := func() (Decoder, bool) {
+		for _, d := range decoders {
+			if d.Sniff != nil && d.Sniff(header) {
+				return d, true
+			}
+		}
+		return Decoder{}, false
+	}
+
+	// ID3v2 tag can be prepended to other formats as well, let the extension
+	// decide in that case
+	if !bytes.HasPrefix(header, []byte("ID3")) {
+		if d, ok := sniff(); ok {
+			return d, true
+		}
+	}
+
+	ext := strings.ToLower(filepath.Ext(audioPath))
+	for _, d := range decoders {
+		for _, e := range d.Extensions {
+			if e == ext {
+				return d, true
+			}
+		}
+	}
+
+	return sniff()
+}
+
+// readHeader reads the first bytes of the file used for sniffing.
+func readHeader(f *os.File) ([]byte, error) {
+
+	header := make([]byte, sniffLen)
+
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, tracerr.Wrap(err)
+	}
+
+	return header[:n], nil
+}
+
+// FormatOf returns the name of the decoder which is able to decode the audio
+// file, returns false if none of the registered decoders support it.
+func FormatOf(audioPath string) (string, bool) {
+
+	f, err := os.Open(audioPath)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	header, err := readHeader(f)
+	if err != nil || len(header) == 0 {
+		return "", false
+	}
+
+	d, ok := findDecoder(audioPath, header)
+	if !ok {
+		return "", false
+	}
+
+	return d.Name, true
+}
+
+// IsSupported checks if the audio file can be decoded by the player.
+func IsSupported(audioPath string) bool {
+	_, ok := FormatOf(audioPath)
+	return ok
+}
+
+// IsAudioExt checks if ext belongs to one of the registered decoders.
+func IsAudioExt(ext string) bool {
+	ext = strings.ToLower(ext)
+	for _, d := range decoders {
+		for _, e := range d.Extensions {
+			if e == ext {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Decode opens the audio file and decodes it using the matching decoder.
+func Decode(audioPath string) (beep.StreamSeekCloser, beep.Format, error) {
+
+	f, err := os.Open(audioPath)
+	if err != nil {
+		return nil, beep.Format{}, tracerr.Wrap(err)
+	}
+
+	header, err := readHeader(f)
+	if err != nil {
+		f.Close()
+		return nil, beep.Format{}, tracerr.Wrap(err)
+	}
+
+	d, ok := findDecoder(audioPath, header)
+	if !ok {
+		f.Close()
+		return nil, beep.Format{}, tracerr.Errorf("unsupported audio format: %s", audioPath)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, beep.Format{}, tracerr.Wrap(err)
+	}
+
+	stream, format, err := d.Decode(f)
+	if err != nil {
+		f.Close()
+		return nil, beep.Format{}, tracerr.Wrap(err)
+	}
+
+	return stream, format, nil
+}
@@ -0,0 +1,79 @@
+package player
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// writes one second of silence as wav file
+func writeWav(t *testing.T, path string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	format := beep.Format{SampleRate: 44100, NumChannels: 2, Precision: 2}
+	silence := beep.Silence(format.SampleRate.N(time.Second))
+
+	if err := wav.Encode(f, silence, format); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+
+	dir := t.TempDir()
+
+	wavPath := filepath.Join(dir, "silence.wav")
+	writeWav(t, wavPath)
+
+	// content wins over the extension
+	noExt := filepath.Join(dir, "silence")
+	writeWav(t, noExt)
+
+	textPath := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(textPath, []byte("not an audio file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	samples := map[string]string{
+		"../test/rap/audio_test.mp3": "mp3",
+		wavPath:                      "wav",
+		noExt:                        "wav",
+		textPath:                     "",
+	}
+
+	for path, want := range samples {
+		got, _ := FormatOf(path)
+		assert.Equal(t, want, got, path)
+	}
+
+	assert.False(t, IsSupported("../test/pop/arbitrary_file.txt"))
+}
+
+func TestGetLength(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "silence.wav")
+	writeWav(t, path)
+
+	got, err := GetLength(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, time.Second, got)
+}
+
+func TestIsAudioExt(t *testing.T) {
+	for _, ext := range []string{".mp3", ".FLAC", ".ogg", ".wav"} {
+		assert.True(t, IsAudioExt(ext), ext)
+	}
+	assert.False(t, IsAudioExt(".txt"))
+}
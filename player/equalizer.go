@@ -0,0 +1,126 @@
+// Copyright (C) 2020  Raziman
+
+package player
+
+import (
+	"math"
+
+	"github.com/faiface/beep"
+	"github.com/ztrue/tracerr"
+)
+
+// EqualizerBands are the center frequencies of the equalizer bands in Hz.
+var EqualizerBands = [...]float64{
+	31, 62, 125, 250, 500, 1000, 2000, 4000, 8000, 16000,
+}
+
+// range of equalizer gain in dB
+const (
+	MinEqualizerGain = -12.0
+	MaxEqualizerGain = 12.0
+)
+
+// bandwidth of each band, about one octave
+const equalizerQ = 1.41
+
+// equalizer is a graphic equalizer made of peaking filters, one for each
+// band. Bands with zero gain are bypassed.
+type equalizer struct {
+	Streamer   beep.Streamer
+	sampleRate beep.SampleRate
+	gains      [len(EqualizerBands)]float64
+	filters    [len(EqualizerBands)]biquad
+}
+
+// newEqualizer returns equalizer with the given gains in dB.
+func newEqualizer(
+	s beep.Streamer, sr beep.SampleRate, gains [len(EqualizerBands)]float64,
+) *equalizer {
+
+	eq := &equalizer{
+		Streamer:   s,
+		sampleRate: sr,
+	}
+
+	eq.setGains(gains)
+
+	return eq
+}
+
+// setGains recalculates the coefficients of the bands. The filter state of
+// active bands is kept so the change does not click.
+func (eq *equalizer) setGains(gains [len(EqualizerBands)]float64) {
+
+	for i, f0 := range EqualizerBands {
+
+		f := &eq.filters[i]
+
+		// state of bypassed band is stale
+		if eq.gains[i] == 0 {
+			f.reset()
+		}
+		eq.gains[i] = gains[i]
+
+		// the band is above nyquist frequency
+		if f0 >= float64(eq.sampleRate)/2 {
+			eq.gains[i] = 0
+			continue
+		}
+
+		// coefficients of peaking filter from audio eq cookbook
+		a := math.Pow(10, gains[i]/40)
+		w := 2 * math.Pi * f0 / float64(eq.sampleRate)
+		alpha := math.Sin(w) / (2 * equalizerQ)
+		a0 := 1 + alpha/a
+
+		f.b0 = (1 + alpha*a) / a0
+		f.b1 = -2 * math.Cos(w) / a0
+		f.b2 = (1 - alpha*a) / a0
+		f.a1 = -2 * math.Cos(w) / a0
+		f.a2 = (1 - alpha/a) / a0
+	}
+}
+
+// Stream implements beep.Streamer.
+func (eq *equalizer) Stream(samples [][2]float64) (n int, ok bool) {
+
+	n, ok = eq.Streamer.Stream(samples)
+
+	for i := range eq.filters {
+
+		if eq.gains[i] == 0 {
+			continue
+		}
+
+		f := &eq.filters[i]
+		for j := range samples[:n] {
+			samples[j][0] = f.process(0, samples[j][0])
+			samples[j][1] = f.process(1, samples[j][1])
+		}
+	}
+
+	return n, ok
+}
+
+// Err implements beep.Streamer.
+func (eq *equalizer) Err() error {
+	return eq.Streamer.Err()
+}
+
+// EqualizerGains converts gains in dB to the gain of each band, values are
+// clamped to the allowed range.
+func EqualizerGains(gains []float64) ([len(EqualizerBands)]float64, error) {
+
+	var result [len(EqualizerBands)]float64
+
+	if len(gains) != len(EqualizerBands) {
+		return result, tracerr.Errorf(
+			"equalizer requires %d bands, got %d", len(EqualizerBands), len(gains))
+	}
+
+	for i, g := range gains {
+		result[i] = math.Max(MinEqualizerGain, math.Min(MaxEqualizerGain, g))
+	}
+
+	return result, nil
+}
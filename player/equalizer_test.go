@@ -0,0 +1,87 @@
+package player
+
+import (
+	"math"
+	"testing"
+
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+)
+
+// peakAfter returns the peak of the sine after passing through the
+// equalizer, skipping the transient at the start.
+func peakAfter(gains [len(EqualizerBands)]float64, freq float64) float64 {
+
+	sr := beep.SampleRate(48000)
+	samples := sine(sr, freq, 0.1, 1)
+
+	s := beep.StreamerFunc(func(buf [][2]float64) (int, bool) {
+		if len(samples) == 0 {
+			return 0, false
+		}
+		n := copy(buf, samples)
+		samples = samples[n:]
+		return n, true
+	})
+
+	eq := newEqualizer(s, sr, gains)
+
+	var peak float64
+	buf := make([][2]float64, 512)
+	for pos := 0; ; {
+		n, ok := eq.Stream(buf)
+		for _, v := range buf[:n] {
+			if pos > int(sr)/2 {
+				peak = math.Max(peak, math.Abs(v[0]))
+			}
+			pos++
+		}
+		if !ok {
+			break
+		}
+	}
+
+	return peak
+}
+
+func TestEqualizer(t *testing.T) {
+
+	var flat [len(EqualizerBands)]float64
+	assert.InDelta(t, 0.1, peakAfter(flat, 1000), 0.001)
+
+	// boosting 1kHz band by 6dB doubles the amplitude of 1kHz sine
+	boost := flat
+	boost[5] = 6
+	assert.InDelta(t, 0.2, peakAfter(boost, 1000), 0.005)
+	// while frequency far from the band is untouched
+	assert.InDelta(t, 0.1, peakAfter(boost, 12000), 0.005)
+
+	cut := flat
+	cut[5] = -6
+	assert.InDelta(t, 0.05, peakAfter(cut, 1000), 0.005)
+}
+
+func TestEqualizerGains(t *testing.T) {
+
+	gains, err := EqualizerGains([]float64{-20, 0, 1, 2, 3, 4, 5, 6, 7, 20})
+	assert.NoError(t, err)
+	assert.Equal(t, MinEqualizerGain, gains[0])
+	assert.Equal(t, 3.0, gains[4])
+	assert.Equal(t, MaxEqualizerGain, gains[9])
+
+	_, err = EqualizerGains([]float64{1, 2})
+	assert.Error(t, err)
+}
+
+func TestPlayerSetEqualizer(t *testing.T) {
+
+	p := New(80)
+
+	err := p.SetEqualizer([]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, p.GetEqualizer())
+
+	err = p.SetEqualizer([]float64{1})
+	assert.Error(t, err)
+	assert.Equal(t, []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, p.GetEqualizer())
+}
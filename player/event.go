@@ -0,0 +1,234 @@
+// Copyright (C) 2020  Raziman
+
+package player
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the playback state of the player.
+type State int
+
+// states of the player
+const (
+	Stopped State = iota
+	Playing
+	Paused
+)
+
+func (s State) String() string {
+	switch s {
+	case Playing:
+		return "playing"
+	case Paused:
+		return "paused"
+	}
+	return "stopped"
+}
+
+// EventType is the kind of change reported by the player.
+type EventType int
+
+// events published by the player
+const (
+	// EventStarted is published when a song starts playing, including when it
+	// takes over the previous song.
+	EventStarted EventType = iota
+	// EventFinished is published when a song ends or is skipped and nothing
+	// is playing afterwards.
+	EventFinished
+	// EventChanged is published when the next song takes over the finished
+	// song without stopping the playback, right before EventStarted.
+	EventChanged
+	// EventSkipped is published when a song is skipped, right before
+	// EventFinished.
+	EventSkipped
+	// EventStopped is published when a song is stopped without finishing.
+	EventStopped
+	// EventPaused is published when the player is paused.
+	EventPaused
+	// EventResumed is published when the player is unpaused.
+	EventResumed
+	// EventSeeked is published when the position of the song is changed.
+	EventSeeked
+	// EventVolume is published when the volume is changed.
+	EventVolume
+	// EventTitle is published when live stream starts playing another song.
+	EventTitle
+	// EventError is published when live stream opened by Run can't be played.
+	EventError
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventStarted:
+		return "started"
+	case EventFinished:
+		return "finished"
+	case EventChanged:
+		return "changed"
+	case EventSkipped:
+		return "skipped"
+	case EventStopped:
+		return "stopped"
+	case EventPaused:
+		return "paused"
+	case EventResumed:
+		return "resumed"
+	case EventSeeked:
+		return "seeked"
+	case EventVolume:
+		return "volume"
+	case EventTitle:
+		return "title"
+	case EventError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// Event describes a change of the player.
+type Event struct {
+	Type EventType
+	// State is the state of the player after the event.
+	State State
+	// Audio is the song concerned by the event.
+	Audio Audio
+	// Prev is the song that Audio took over, it is only set for EventChanged.
+	Prev Audio
+	// Position is the new position for EventSeeked and the position where the
+	// song was left off for EventSkipped, EventStopped and EventFinished.
+	Position time.Duration
+	// Volume is the new volume, it is only set for EventVolume.
+	Volume float64
+	// Title is the song being played by live stream, it is only set for
+	// EventTitle.
+	Title string
+	// Err is the reason the song can't be played, it is only set for
+	// EventError.
+	Err error
+}
+
+// subscriber queues the events so publishing never waits for the receiver.
+// Events are delivered in the order they are published.
+type subscriber struct {
+	events chan Event
+	mu     sync.Mutex
+	queue  []Event
+	notify chan struct{}
+	done   chan struct{}
+}
+
+func newSubscriber() *subscriber {
+
+	s := &subscriber{
+		events: make(chan Event),
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+// push queues the event.
+func (s *subscriber) push(e Event) {
+
+	s.mu.Lock()
+	s.queue = append(s.queue, e)
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers the queued events until the subscriber is closed.
+func (s *subscriber) run() {
+
+	defer close(s.events)
+
+	for {
+
+		select {
+		case <-s.done:
+			return
+		case <-s.notify:
+		}
+
+		s.mu.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+
+		for _, e := range queue {
+			select {
+			case s.events <- e:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+// close stops delivering events and closes the channel.
+func (s *subscriber) close() {
+	close(s.done)
+}
+
+// Subscribe returns a channel receiving the events published from now on. The
+// channel is closed by Unsubscribe or when the player is closed.
+func (p *Player) Subscribe() <-chan Event {
+
+	s := newSubscriber()
+
+	p.subMu.Lock()
+	p.subscribers = append(p.subscribers, s)
+	p.subMu.Unlock()
+
+	return s.events
+}
+
+// Unsubscribe stops delivering events to the channel returned by Subscribe.
+func (p *Player) Unsubscribe(events <-chan Event) {
+
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+
+	for i, s := range p.subscribers {
+		if s.events == events {
+			p.subscribers = append(p.subscribers[:i], p.subscribers[i+1:]...)
+			s.close()
+			return
+		}
+	}
+}
+
+// publish sends the event to every subscriber. It must be called with p.mu
+// held so that the events are in the same order as the state changes.
+func (p *Player) publish(e Event) {
+
+	e.State = p.state
+
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+
+	for _, s := range p.subscribers {
+		s.push(e)
+	}
+}
+
+// closeSubscribers closes every channel returned by Subscribe.
+func (p *Player) closeSubscribers() {
+
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+
+	for _, s := range p.subscribers {
+		s.close()
+	}
+	p.subscribers = nil
+}
@@ -0,0 +1,233 @@
+package player
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// receives the next event or fails after a while
+func nextEvent(t *testing.T, events <-chan Event) Event {
+
+	t.Helper()
+
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatal("events channel is closed")
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event is published")
+	}
+
+	return Event{}
+}
+
+// returns player playing one second of silence to the null output
+func newTestPlayer(t *testing.T) (*Player, <-chan Event, Audio) {
+
+	path := filepath.Join(t.TempDir(), "silence.wav")
+	writeWav(t, path)
+
+	p := New(80)
+	assert.NoError(t, p.SetOutput(NewNullOutput()))
+	t.Cleanup(func() { p.Close() })
+
+	events := p.Subscribe()
+	audio := testAudio(path)
+
+	if err := p.Run(audio); err != nil {
+		t.Fatal(err)
+	}
+
+	e := nextEvent(t, events)
+	assert.Equal(t, EventStarted, e.Type)
+	assert.Equal(t, Playing, e.State)
+	assert.Equal(t, audio, e.Audio)
+
+	return p, events, audio
+}
+
+func TestPlayerEvents(t *testing.T) {
+
+	p, events, audio := newTestPlayer(t)
+
+	p.Pause()
+	assert.Equal(t, Paused, p.State())
+	assert.True(t, p.IsPaused())
+	// pausing twice does nothing
+	p.Pause()
+
+	p.TogglePause()
+	assert.True(t, p.IsRunning())
+
+	assert.NoError(t, p.Seek(0))
+	p.SetVolume(-0.5)
+	p.Skip()
+	assert.Equal(t, Stopped, p.State())
+	// nothing to skip
+	p.Skip()
+
+	tests := []struct {
+		typ   EventType
+		state State
+	}{
+		{EventPaused, Paused},
+		{EventResumed, Playing},
+		{EventSeeked, Playing},
+		{EventVolume, Playing},
+		{EventSkipped, Stopped},
+		{EventFinished, Stopped},
+	}
+
+	for _, test := range tests {
+		e := nextEvent(t, events)
+		assert.Equal(t, test.typ, e.Type, test.typ.String())
+		assert.Equal(t, test.state, e.State, test.typ.String())
+		assert.Equal(t, audio, e.Audio, test.typ.String())
+	}
+
+	p.Unsubscribe(events)
+
+	select {
+	case e, ok := <-events:
+		assert.False(t, ok, "unexpected event %s", e.Type)
+	case <-time.After(time.Second):
+		t.Fatal("events channel is not closed")
+	}
+}
+
+func TestPlayerStop(t *testing.T) {
+
+	p, events, _ := newTestPlayer(t)
+
+	p.Stop()
+	assert.Equal(t, Stopped, p.State())
+
+	e := nextEvent(t, events)
+	assert.Equal(t, EventStopped, e.Type)
+	assert.Less(t, int64(e.Position), int64(time.Second))
+
+	// stopped song does not finish
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event %s", e.Type)
+	case <-time.After(1500 * time.Millisecond):
+	}
+}
+
+func TestPlayerRunReplaces(t *testing.T) {
+
+	p, events, audio := newTestPlayer(t)
+
+	p.Pause()
+	assert.Equal(t, EventPaused, nextEvent(t, events).Type)
+	ctrl := p.ctrl
+
+	assert.NoError(t, p.Run(audio))
+
+	e := nextEvent(t, events)
+	assert.Equal(t, EventStopped, e.Type)
+	assert.Equal(t, audio, e.Audio)
+	assert.Equal(t, EventStarted, nextEvent(t, events).Type)
+
+	// the paused song is no longer streamed to the output
+	p.output.Lock()
+	assert.Nil(t, ctrl.Streamer)
+	p.output.Unlock()
+}
+
+func TestPlayerSetNext(t *testing.T) {
+
+	p, events, audio := newTestPlayer(t)
+
+	dir := t.TempDir()
+	first := testAudio(filepath.Join(dir, "first.wav"))
+	second := testAudio(filepath.Join(dir, "second.wav"))
+	writeWav(t, first.Path())
+	writeWav(t, second.Path())
+
+	p.SetNext(first)
+	time.Sleep(200 * time.Millisecond)
+
+	// the song opened ahead is dropped for the new next song
+	p.SetNext(second)
+
+	e := nextEvent(t, events)
+	assert.Equal(t, EventChanged, e.Type)
+	assert.Equal(t, audio, e.Prev)
+	assert.Equal(t, second, e.Audio)
+}
+
+func TestPlayerSetNextNil(t *testing.T) {
+
+	p, events, audio := newTestPlayer(t)
+
+	next := testAudio(filepath.Join(t.TempDir(), "next.wav"))
+	writeWav(t, next.Path())
+
+	p.SetNext(next)
+	time.Sleep(200 * time.Millisecond)
+
+	// the song opened ahead is dropped before it starts
+	p.SetNext(nil)
+	p.output.Lock()
+	assert.Nil(t, p.seq.next)
+	p.output.Unlock()
+
+	e := nextEvent(t, events)
+	assert.Equal(t, EventFinished, e.Type)
+	assert.Equal(t, audio, e.Audio)
+}
+
+func TestPlayerFadeOut(t *testing.T) {
+
+	p, _, _ := newTestPlayer(t)
+	volume := p.GetVolume()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		p.SetVolume(-0.5)
+	}()
+
+	p.FadeOut(200 * time.Millisecond)
+	assert.Equal(t, Paused, p.State())
+
+	// volume changed during the fade is kept
+	assert.Equal(t, volume-0.5, p.GetVolume())
+	p.output.Lock()
+	assert.Equal(t, volume-0.5, p.vol.Volume)
+	assert.Equal(t, 0.0, p.fader.Volume)
+	p.output.Unlock()
+}
+
+func TestPlayerFinishPosition(t *testing.T) {
+
+	_, events, _ := newTestPlayer(t)
+
+	e := nextEvent(t, events)
+	assert.Equal(t, EventFinished, e.Type)
+	assert.Equal(t, time.Second, e.Position)
+}
+
+func TestSubscriber(t *testing.T) {
+
+	s := newSubscriber()
+
+	// publishing does not wait for the receiver
+	for i := 0; i < 100; i++ {
+		s.push(Event{Volume: float64(i)})
+	}
+
+	for i := 0; i < 100; i++ {
+		e := nextEvent(t, s.events)
+		assert.Equal(t, float64(i), e.Volume)
+	}
+
+	s.close()
+	_, ok := <-s.events
+	assert.False(t, ok)
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2020  Raziman
+
+package player
+
+import (
+	"github.com/faiface/beep"
+	"github.com/ztrue/tracerr"
+)
+
+// looper repeats a section of the stream. Positions are in samples of the
+// stream, so the section is repeated precisely regardless of the speed and
+// the resampling that comes after it.
+type looper struct {
+	Streamer beep.StreamSeeker
+	// section being repeated, end is zero when there is no loop
+	start, end int
+}
+
+// set sets the section to be repeated and seeks to its start if the current
+// position is outside of the section.
+func (l *looper) set(start, end int) error {
+
+	if start < 0 || end <= start || end > l.Streamer.Len() {
+		return tracerr.Errorf("invalid loop section %d-%d", start, end)
+	}
+
+	l.start, l.end = start, end
+
+	pos := l.Streamer.Position()
+	if pos < start || pos >= end {
+		return l.Streamer.Seek(start)
+	}
+
+	return nil
+}
+
+// clear stops repeating the section.
+func (l *looper) clear() {
+	l.start, l.end = 0, 0
+}
+
+// active returns true if a section is being repeated.
+func (l *looper) active() bool {
+	return l.end > 0
+}
+
+// Stream implements beep.Streamer.
+func (l *looper) Stream(samples [][2]float64) (n int, ok bool) {
+
+	if !l.active() {
+		return l.Streamer.Stream(samples)
+	}
+
+	for n < len(samples) {
+
+		pos := l.Streamer.Position()
+		if pos >= l.end {
+			if err := l.Streamer.Seek(l.start); err != nil {
+				return n, n > 0
+			}
+			pos = l.start
+		}
+
+		// never stream past the end of the section
+		toStream := len(samples) - n
+		if l.end-pos < toStream {
+			toStream = l.end - pos
+		}
+
+		sn, sok := l.Streamer.Stream(samples[n : n+toStream])
+		n += sn
+
+		if !sok || sn == 0 {
+			return n, n > 0
+		}
+	}
+
+	return n, true
+}
+
+// Err implements beep.Streamer.
+func (l *looper) Err() error {
+	return l.Streamer.Err()
+}
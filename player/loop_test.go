@@ -0,0 +1,92 @@
+package player
+
+import (
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRamp returns stream where the value of each sample is its position
+// divided by 1000.
+func newRamp(n int) beep.StreamSeeker {
+
+	format := beep.Format{SampleRate: 100, NumChannels: 2, Precision: 2}
+	buf := beep.NewBuffer(format)
+
+	pos := 0
+	buf.Append(beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		if pos == n {
+			return 0, false
+		}
+		if len(samples) > n-pos {
+			samples = samples[:n-pos]
+		}
+		for i := range samples {
+			v := float64(pos) / 1000
+			samples[i] = [2]float64{v, v}
+			pos++
+		}
+		return len(samples), true
+	}))
+
+	return buf.Streamer(0, buf.Len())
+}
+
+func TestLooper(t *testing.T) {
+
+	l := &looper{Streamer: newRamp(100)}
+
+	assert.Error(t, l.set(20, 10))
+	assert.Error(t, l.set(10, 200))
+	assert.False(t, l.active())
+
+	// seeks to the start of the section
+	assert.NoError(t, l.set(10, 15))
+	assert.True(t, l.active())
+
+	samples := make([][2]float64, 12)
+	n, ok := l.Stream(samples)
+	assert.Equal(t, 12, n)
+	assert.True(t, ok)
+
+	expected := []int{10, 11, 12, 13, 14, 10, 11, 12, 13, 14, 10, 11}
+	for i, pos := range expected {
+		assert.InDelta(t, float64(pos)/1000, samples[i][0], 0.0001)
+	}
+
+	// plays through the end of the section after clearing
+	l.clear()
+	n, ok = l.Stream(samples)
+	assert.Equal(t, 12, n)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.012, samples[0][0], 0.0001)
+	assert.InDelta(t, 0.023, samples[11][0], 0.0001)
+}
+
+func TestSequencerLoop(t *testing.T) {
+
+	seq := &sequencer{
+		current: newTestTrack("first", 0.25, 30),
+		fade:    time.Second,
+		preload: func(*sequencer) {
+			t.Error("next song must not be requested while looping")
+		},
+		change: func(prev, next *track) {
+			t.Error("song must not change while looping")
+		},
+		finish: func(prev *track) {
+			t.Error("song must not finish while looping")
+		},
+	}
+	seq.next = newTestTrack("second", 0.5, 30)
+
+	assert.NoError(t, seq.current.loop.set(20, 30))
+
+	samples := make([][2]float64, 100)
+	n, ok := seq.Stream(samples)
+	assert.Equal(t, 100, n)
+	assert.True(t, ok)
+	assert.Equal(t, testAudio("first"), seq.current.audio)
+}
@@ -0,0 +1,254 @@
+// Copyright (C) 2020  Raziman
+
+package player
+
+import (
+	"math"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/ztrue/tracerr"
+)
+
+// ReferenceLoudness is the target loudness of ReplayGain 2.0 in LUFS.
+const ReferenceLoudness = -18.0
+
+// gating thresholds of EBU R128
+const (
+	absoluteGate = -70.0
+	relativeGate = -10.0
+)
+
+// oversampling factor and filter taps per phase of the true peak meter
+const (
+	truePeakFactor = 4
+	truePeakTaps   = 12
+)
+
+// LoudnessMeter measures integrated loudness and true peak of audio as
+// described in EBU R128 and ITU-R BS.1770.
+type LoudnessMeter struct {
+	channels int
+	// K-weighting filters
+	shelf, highpass biquad
+
+	// samples per 100ms, blocks are 400ms long with 75% overlap
+	hopSize int
+	hopPos  int
+	hopSum  float64
+	hops    []float64
+	// mean square of each 400ms block
+	blocks []float64
+
+	// interpolation filter and recent samples of each channel
+	phases   [truePeakFactor][truePeakTaps]float64
+	history  [2][truePeakTaps]float64
+	truePeak float64
+}
+
+// NewLoudnessMeter returns a meter for audio of the given format.
+func NewLoudnessMeter(format beep.Format) *LoudnessMeter {
+
+	fs := float64(format.SampleRate)
+
+	channels := format.NumChannels
+	if channels < 1 || channels > 2 {
+		channels = 2
+	}
+
+	m := &LoudnessMeter{
+		channels: channels,
+		hopSize:  format.SampleRate.N(100 * time.Millisecond),
+	}
+
+	// pre-filter which models the acoustic effect of the head
+	f0, gain, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / fs)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	m.shelf = biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	// RLB weighting curve
+	f0, q = 38.13547087602444, 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / fs)
+	a0 = 1 + k/q + k*k
+	m.highpass = biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	// windowed sinc interpolation filter split into polyphase components
+	n := truePeakFactor * truePeakTaps
+	center := float64(n-1) / 2
+	for i := 0; i < n; i++ {
+		x := (float64(i) - center) / truePeakFactor
+		h := 1.0
+		if x != 0 {
+			h = math.Sin(math.Pi*x) / (math.Pi * x)
+		}
+		window := 0.5 - 0.5*math.Cos(2*math.Pi*(float64(i)+0.5)/float64(n))
+		m.phases[i%truePeakFactor][i/truePeakFactor] = h * window
+	}
+
+	// unity gain for every phase
+	for p := range m.phases {
+		var sum float64
+		for _, h := range m.phases[p] {
+			sum += h
+		}
+		for j := range m.phases[p] {
+			m.phases[p][j] /= sum
+		}
+	}
+
+	return m
+}
+
+// Write feeds samples to the meter.
+func (m *LoudnessMeter) Write(samples [][2]float64) {
+
+	for _, s := range samples {
+
+		for ch := 0; ch < m.channels; ch++ {
+			y := m.highpass.process(ch, m.shelf.process(ch, s[ch]))
+			m.hopSum += y * y
+			m.writePeak(ch, s[ch])
+		}
+
+		m.hopPos++
+		if m.hopPos < m.hopSize {
+			continue
+		}
+
+		m.hops = append(m.hops, m.hopSum)
+		m.hopPos = 0
+		m.hopSum = 0
+
+		if len(m.hops) < 4 {
+			continue
+		}
+
+		last := m.hops[len(m.hops)-4:]
+		m.blocks = append(m.blocks,
+			(last[0]+last[1]+last[2]+last[3])/float64(4*m.hopSize))
+
+		// only the last three hops are needed for the next block
+		m.hops = append(m.hops[:0], last[1:]...)
+	}
+}
+
+// writePeak oversamples the channel and records the highest peak.
+func (m *LoudnessMeter) writePeak(ch int, x float64) {
+
+	h := &m.history[ch]
+	copy(h[1:], h[:truePeakTaps-1])
+	h[0] = x
+
+	for p := range m.phases {
+		var y float64
+		for j, c := range m.phases[p] {
+			y += c * h[j]
+		}
+		m.truePeak = math.Max(m.truePeak, math.Abs(y))
+	}
+
+	m.truePeak = math.Max(m.truePeak, math.Abs(x))
+}
+
+// Integrated returns the gated integrated loudness in LUFS, negative infinity
+// is returned for silence.
+func (m *LoudnessMeter) Integrated() float64 {
+	return IntegratedLoudness(m)
+}
+
+// TruePeak returns the highest oversampled peak as linear sample value.
+func (m *LoudnessMeter) TruePeak() float64 {
+	return m.truePeak
+}
+
+// IntegratedLoudness returns the gated loudness over the blocks of all meters.
+// This is used to measure album loudness.
+func IntegratedLoudness(meters ...*LoudnessMeter) float64 {
+
+	var blocks []float64
+	for _, m := range meters {
+		for _, b := range m.blocks {
+			if blockLoudness(b) > absoluteGate {
+				blocks = append(blocks, b)
+			}
+		}
+	}
+
+	if len(blocks) == 0 {
+		return math.Inf(-1)
+	}
+
+	threshold := blockLoudness(mean(blocks)) + relativeGate
+
+	var gated []float64
+	for _, b := range blocks {
+		if blockLoudness(b) > threshold {
+			gated = append(gated, b)
+		}
+	}
+
+	return blockLoudness(mean(gated))
+}
+
+// ReplayGainOf returns the gain in dB to bring the loudness to the reference
+// loudness.
+func ReplayGainOf(loudness float64) float64 {
+	if math.IsInf(loudness, 0) || math.IsNaN(loudness) {
+		return 0
+	}
+	return ReferenceLoudness - loudness
+}
+
+// MeasureLoudness decodes the whole audio file and measures its loudness.
+func MeasureLoudness(audioPath string) (*LoudnessMeter, error) {
+
+	stream, format, err := Decode(audioPath)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+	defer stream.Close()
+
+	m := NewLoudnessMeter(format)
+	buf := make([][2]float64, 4096)
+
+	for {
+		n, ok := stream.Stream(buf)
+		m.Write(buf[:n])
+		if !ok {
+			break
+		}
+	}
+
+	if err := stream.Err(); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	return m, nil
+}
+
+func blockLoudness(meanSquare float64) float64 {
+	return -0.691 + 10*math.Log10(meanSquare)
+}
+
+func mean(xs []float64) float64 {
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
@@ -0,0 +1,129 @@
+package player
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// sine returns stereo sine wave of the given frequency and amplitude.
+func sine(sr beep.SampleRate, freq, amp float64, d float64) [][2]float64 {
+	samples := make([][2]float64, int(float64(sr)*d))
+	for i := range samples {
+		v := amp * math.Sin(2*math.Pi*freq*float64(i)/float64(sr))
+		samples[i] = [2]float64{v, v}
+	}
+	return samples
+}
+
+func TestLoudnessMeter(t *testing.T) {
+
+	format := beep.Format{SampleRate: 48000, NumChannels: 2, Precision: 2}
+
+	// a full scale 997Hz sine in both channels measures 0 LUFS
+	m := NewLoudnessMeter(format)
+	m.Write(sine(format.SampleRate, 997, 1, 5))
+	assert.InDelta(t, 0, m.Integrated(), 0.1)
+	assert.InDelta(t, 1, m.TruePeak(), 0.02)
+
+	m = NewLoudnessMeter(format)
+	m.Write(sine(format.SampleRate, 997, 0.1, 5))
+	assert.InDelta(t, -20, m.Integrated(), 0.1)
+	assert.InDelta(t, 2, ReplayGainOf(m.Integrated()), 0.1)
+
+	// quiet part is removed by the relative gate, only the blocks overlapping
+	// both parts are left
+	m = NewLoudnessMeter(format)
+	m.Write(sine(format.SampleRate, 997, 0.1, 5))
+	m.Write(sine(format.SampleRate, 997, 0.001, 5))
+	assert.InDelta(t, -20, m.Integrated(), 0.2)
+
+	// a single channel is 3dB quieter than both
+	mono := beep.Format{SampleRate: 48000, NumChannels: 1, Precision: 2}
+	m = NewLoudnessMeter(mono)
+	m.Write(sine(mono.SampleRate, 997, 1, 5))
+	assert.InDelta(t, -3.01, m.Integrated(), 0.1)
+
+	m = NewLoudnessMeter(format)
+	m.Write(make([][2]float64, 48000))
+	assert.True(t, math.IsInf(m.Integrated(), -1))
+	assert.Equal(t, 0.0, ReplayGainOf(m.Integrated()))
+
+	// true peak of a sine sampled off its crest exceeds the sample peak
+	m = NewLoudnessMeter(format)
+	m.Write(sine(format.SampleRate, 12000, 0.5, 1))
+	assert.InDelta(t, 0.5, m.TruePeak(), 0.03)
+}
+
+func TestIntegratedLoudness(t *testing.T) {
+
+	format := beep.Format{SampleRate: 44100, NumChannels: 2, Precision: 2}
+
+	loud := NewLoudnessMeter(format)
+	loud.Write(sine(format.SampleRate, 997, 0.5, 3))
+
+	quiet := NewLoudnessMeter(format)
+	quiet.Write(sine(format.SampleRate, 997, 0.25, 3))
+
+	album := IntegratedLoudness(loud, quiet)
+	assert.True(t, album < loud.Integrated())
+	assert.True(t, album > quiet.Integrated())
+}
+
+// writeSine writes 3 seconds of 997Hz sine wave to a wav file.
+func writeSine(t *testing.T, audioPath string, amp float64) {
+
+	format := beep.Format{SampleRate: 44100, NumChannels: 2, Precision: 2}
+
+	samples := sine(format.SampleRate, 997, amp, 3)
+	streamer := beep.StreamerFunc(func(buf [][2]float64) (int, bool) {
+		if len(samples) == 0 {
+			return 0, false
+		}
+		n := copy(buf, samples)
+		samples = samples[n:]
+		return n, true
+	})
+
+	f, err := os.Create(audioPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := wav.Encode(f, streamer, format); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMeasureLoudness(t *testing.T) {
+
+	dir := t.TempDir()
+	quietPath := filepath.Join(dir, "quiet.wav")
+	loudPath := filepath.Join(dir, "loud.wav")
+
+	writeSine(t, quietPath, 0.1)
+	writeSine(t, loudPath, 0.2)
+
+	quiet, err := MeasureLoudness(quietPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loud, err := MeasureLoudness(loudPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// doubling the amplitude raises the loudness by 6dB
+	assert.InDelta(t, 6.02, loud.Integrated()-quiet.Integrated(), 0.1)
+	assert.InDelta(t, 2, loud.TruePeak()/quiet.TruePeak(), 0.01)
+
+	_, err = MeasureLoudness(filepath.Join(dir, "missing.wav"))
+	assert.Error(t, err)
+}
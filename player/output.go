@@ -0,0 +1,317 @@
+// Copyright (C) 2020  Raziman
+
+package player
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/speaker"
+	"github.com/ztrue/tracerr"
+)
+
+// Output consumes the samples produced by the player. Streamers passed to
+// Play are mixed together and removed once they are drained.
+type Output interface {
+	// Init prepares the output to consume samples at the sample rate in
+	// chunks of bufferSize samples.
+	Init(sr beep.SampleRate, bufferSize int) error
+	// Play adds the streamer to the output.
+	Play(s beep.Streamer)
+	// Lock stops the output from consuming samples, streamers being played
+	// must only be modified while the output is locked.
+	Lock()
+	// Unlock resumes consuming samples.
+	Unlock()
+	// Close stops the output and releases its resources.
+	Close() error
+}
+
+// pauser is implemented by outputs which stop consuming samples while the
+// player is paused, instead of consuming the silence of the paused song.
+type pauser interface {
+	// setPaused is called while the output is locked.
+	setPaused(paused bool)
+}
+
+// NewOutput returns output from its name which is one of "speaker", "null" or
+// the path of a wav file, optionally prefixed by "wav:".
+func NewOutput(name string) (Output, error) {
+
+	switch {
+	case name == "" || name == "speaker":
+		return &SpeakerOutput{}, nil
+	case name == "null":
+		return NewNullOutput(), nil
+	case strings.HasPrefix(name, "wav:"):
+		return NewWavOutput(strings.TrimPrefix(name, "wav:")), nil
+	case strings.EqualFold(filepath.Ext(name), ".wav"):
+		return NewWavOutput(name), nil
+	}
+
+	return nil, tracerr.Errorf("unknown output: %s", name)
+}
+
+// SpeakerOutput plays the samples on the sound card.
+type SpeakerOutput struct{}
+
+// Init implements Output.
+func (SpeakerOutput) Init(sr beep.SampleRate, bufferSize int) error {
+	return speaker.Init(sr, bufferSize)
+}
+
+// Play implements Output.
+func (SpeakerOutput) Play(s beep.Streamer) {
+	speaker.Play(s)
+}
+
+// Lock implements Output.
+func (SpeakerOutput) Lock() {
+	speaker.Lock()
+}
+
+// Unlock implements Output.
+func (SpeakerOutput) Unlock() {
+	speaker.Unlock()
+}
+
+// Close implements Output.
+func (SpeakerOutput) Close() error {
+	speaker.Close()
+	return nil
+}
+
+// clockOutput consumes samples in real time like a sound card would and
+// hands them to write.
+type clockOutput struct {
+	mu    sync.Mutex
+	mixer beep.Mixer
+	done  chan struct{}
+	wg    sync.WaitGroup
+	// write is called without the lock held, it is only called while there
+	// is something to play
+	write func(samples [][2]float64) error
+	// nothing is consumed while the player is paused
+	paused bool
+}
+
+// Init implements Output.
+func (o *clockOutput) Init(sr beep.SampleRate, bufferSize int) error {
+
+	if o.done != nil {
+		return tracerr.New("output has already been initialized")
+	}
+
+	o.done = make(chan struct{})
+	buf := make([][2]float64, bufferSize)
+	ticker := time.NewTicker(sr.D(bufferSize))
+
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-o.done:
+				return
+			case <-ticker.C:
+			}
+
+			o.mu.Lock()
+			playing := o.mixer.Len() > 0 && !o.paused
+			if playing {
+				o.mixer.Stream(buf)
+			}
+			o.mu.Unlock()
+
+			if !playing || o.write == nil {
+				continue
+			}
+
+			// there is no way to report the error from here
+			if err := o.write(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// setPaused implements pauser.
+func (o *clockOutput) setPaused(paused bool) {
+	o.paused = paused
+}
+
+// Play implements Output.
+func (o *clockOutput) Play(s beep.Streamer) {
+	o.mu.Lock()
+	o.mixer.Add(s)
+	o.mu.Unlock()
+}
+
+// Lock implements Output.
+func (o *clockOutput) Lock() {
+	o.mu.Lock()
+}
+
+// Unlock implements Output.
+func (o *clockOutput) Unlock() {
+	o.mu.Unlock()
+}
+
+// Close implements Output.
+func (o *clockOutput) Close() error {
+
+	if o.done == nil {
+		return nil
+	}
+
+	select {
+	case <-o.done:
+	default:
+		close(o.done)
+	}
+	o.wg.Wait()
+
+	return nil
+}
+
+// NullOutput discards the samples. It is useful where there is no sound card.
+type NullOutput struct {
+	clockOutput
+}
+
+// NewNullOutput returns output which discards the samples.
+func NewNullOutput() *NullOutput {
+	return &NullOutput{}
+}
+
+// WavOutput writes the samples to a 16-bit stereo wav file. Only the time
+// where something is being played is written, nothing is written while the
+// player is paused.
+type WavOutput struct {
+	clockOutput
+	path string
+	file *os.File
+	// number of bytes of the samples written
+	written    uint32
+	sampleRate beep.SampleRate
+}
+
+// NewWavOutput returns output which writes to the wav file at path.
+func NewWavOutput(path string) *WavOutput {
+	o := &WavOutput{path: path}
+	o.write = o.writeSamples
+	return o
+}
+
+// size of the wav header in bytes
+const wavHeaderSize = 44
+
+// Init implements Output.
+func (o *WavOutput) Init(sr beep.SampleRate, bufferSize int) error {
+
+	f, err := os.Create(o.path)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	o.file = f
+	o.sampleRate = sr
+
+	// sizes are filled in when the output is closed
+	err = o.writeHeader()
+	if err == nil {
+		_, err = f.Seek(wavHeaderSize, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return tracerr.Wrap(err)
+	}
+
+	return o.clockOutput.Init(sr, bufferSize)
+}
+
+// writeHeader writes the wav header at the start of the file.
+func (o *WavOutput) writeHeader() error {
+
+	const (
+		channels  = 2
+		precision = 2
+	)
+
+	header := make([]byte, wavHeaderSize)
+	le := binary.LittleEndian
+
+	copy(header[0:], "RIFF")
+	le.PutUint32(header[4:], wavHeaderSize-8+o.written)
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	le.PutUint32(header[16:], 16)
+	// pcm
+	le.PutUint16(header[20:], 1)
+	le.PutUint16(header[22:], channels)
+	le.PutUint32(header[24:], uint32(o.sampleRate))
+	le.PutUint32(header[28:], uint32(o.sampleRate)*channels*precision)
+	le.PutUint16(header[32:], channels*precision)
+	le.PutUint16(header[34:], precision*8)
+	copy(header[36:], "data")
+	le.PutUint32(header[40:], o.written)
+
+	_, err := o.file.WriteAt(header, 0)
+	return err
+}
+
+// writeSamples appends the samples to the file as 16-bit pcm.
+func (o *WavOutput) writeSamples(samples [][2]float64) error {
+
+	buf := make([]byte, len(samples)*4)
+
+	for i, s := range samples {
+		for c := range s {
+			v := math.Max(-1, math.Min(1, s[c]))
+			binary.LittleEndian.PutUint16(buf[i*4+c*2:], uint16(int16(v*math.MaxInt16)))
+		}
+	}
+
+	n, err := o.file.Write(buf)
+	o.written += uint32(n)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// Close implements Output. The header is updated with the final size.
+func (o *WavOutput) Close() error {
+
+	o.clockOutput.Close()
+
+	if o.file == nil {
+		return nil
+	}
+
+	err := o.writeHeader()
+	if err != nil {
+		o.file.Close()
+		return tracerr.Wrap(err)
+	}
+
+	err = o.file.Close()
+	o.file = nil
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
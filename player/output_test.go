@@ -0,0 +1,172 @@
+package player
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOutput(t *testing.T) {
+
+	tests := []struct {
+		name string
+		want Output
+	}{
+		{"", &SpeakerOutput{}},
+		{"speaker", &SpeakerOutput{}},
+		{"null", NewNullOutput()},
+		{"wav:/tmp/out", NewWavOutput("/tmp/out")},
+		{"/tmp/out.WAV", NewWavOutput("/tmp/out.WAV")},
+	}
+
+	for _, test := range tests {
+		got, err := NewOutput(test.name)
+		if assert.NoError(t, err, test.name) {
+			assert.IsType(t, test.want, got, test.name)
+		}
+	}
+
+	o, _ := NewOutput("wav:/tmp/out")
+	assert.Equal(t, "/tmp/out", o.(*WavOutput).path)
+
+	_, err := NewOutput("alsa")
+	assert.Error(t, err)
+}
+
+// waits until the output has consumed every streamer
+func waitDrained(t *testing.T, o *clockOutput) {
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		o.Lock()
+		n := o.mixer.Len()
+		o.Unlock()
+
+		if n == 0 {
+			return
+		}
+
+		select {
+		case <-timeout:
+			t.Fatal("output is not drained")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestWavOutput(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "out.wav")
+	sr := beep.SampleRate(8000)
+	bufferSize := 80
+
+	o := NewWavOutput(path)
+	if err := o.Init(sr, bufferSize); err != nil {
+		t.Fatal(err)
+	}
+
+	// 0.1 second of constant value
+	samples := 800
+	o.Play(beep.Take(samples, beep.StreamerFunc(func(buf [][2]float64) (int, bool) {
+		for i := range buf {
+			buf[i] = [2]float64{0.5, -0.5}
+		}
+		return len(buf), true
+	})))
+
+	waitDrained(t, &o.clockOutput)
+	assert.NoError(t, o.Close())
+	// closing twice does nothing
+	assert.NoError(t, o.Close())
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	le := binary.LittleEndian
+	size := le.Uint32(data[40:])
+
+	assert.Equal(t, "RIFF", string(data[:4]))
+	assert.Equal(t, "WAVE", string(data[8:12]))
+	assert.Equal(t, uint32(len(data)-8), le.Uint32(data[4:]))
+	assert.Equal(t, uint32(sr), le.Uint32(data[24:]))
+	assert.Equal(t, uint32(len(data)-wavHeaderSize), size)
+	// whole buffers are written, drained streamer is only removed by the
+	// mixer on the next buffer
+	assert.Zero(t, size%uint32(bufferSize*4))
+	assert.GreaterOrEqual(t, size, uint32(samples*4))
+	assert.LessOrEqual(t, size, uint32((samples+bufferSize)*4))
+
+	assert.Equal(t, int16(16383), int16(le.Uint16(data[44:])))
+	assert.Equal(t, int16(-16383), int16(le.Uint16(data[46:])))
+}
+
+func TestPlayerNullOutput(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "silence.wav")
+	writeWav(t, path)
+
+	p := New(80)
+	assert.NoError(t, p.SetOutput(NewNullOutput()))
+
+	events := p.Subscribe()
+
+	if err := p.Run(testAudio(path)); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	// output cannot be changed while playing
+	assert.Error(t, p.SetOutput(NewNullOutput()))
+
+	e := nextEvent(t, events)
+	assert.Equal(t, EventStarted, e.Type)
+
+	e = nextEvent(t, events)
+	assert.Equal(t, EventFinished, e.Type)
+	assert.Equal(t, path, e.Audio.Path())
+}
+
+func TestPlayerWavOutputPause(t *testing.T) {
+
+	dir := t.TempDir()
+	songPath := filepath.Join(dir, "silence.wav")
+	writeWav(t, songPath)
+
+	outPath := filepath.Join(dir, "out.wav")
+	p := New(80)
+	assert.NoError(t, p.SetOutput(NewWavOutput(outPath)))
+
+	events := p.Subscribe()
+
+	if err := p.Run(testAudio(songPath)); err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing is written while paused
+	p.Pause()
+	time.Sleep(500 * time.Millisecond)
+	p.Play()
+
+	for e := nextEvent(t, events); e.Type != EventFinished; e = nextEvent(t, events) {
+	}
+	assert.NoError(t, p.Close())
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// one second of song with a buffer or two of slack
+	size := binary.LittleEndian.Uint32(data[40:])
+	sr := p.sampleRate
+	assert.GreaterOrEqual(t, size, uint32(sr.N(time.Second)*4))
+	assert.LessOrEqual(t, size, uint32(sr.N(1250*time.Millisecond)*4))
+}
@@ -2,14 +2,13 @@
 package player
 
 import (
-	"os"
+	"context"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/faiface/beep"
 	"github.com/faiface/beep/effects"
-	"github.com/faiface/beep/mp3"
-	"github.com/faiface/beep/speaker"
 	"github.com/ztrue/tracerr"
 )
 
@@ -18,22 +17,50 @@ type Audio interface {
 	Path() string
 }
 
-type Player struct {
-	hasInit   bool
-	isRunning bool
-	volume    float64
-
-	vol              *effects.Volume
-	ctrl             *beep.Ctrl
-	format           *beep.Format
-	length           time.Duration
-	currentSong      Audio
-	streamSeekCloser beep.StreamSeekCloser
+// range of playback speed
+const (
+	MinSpeed = 0.5
+	MaxSpeed = 2.0
+)
 
-	songFinish func(Audio)
-	songStart  func(Audio)
-	songSkip   func(Audio)
-	mu         sync.Mutex
+// Player plays songs one after another. Changes of the player are published
+// as events to the subscribers, see Subscribe.
+type Player struct {
+	hasInit bool
+	state   State
+	volume  float64
+	output  Output
+
+	vol         *effects.Volume
+	resampler   *beep.Resampler
+	speed       float64
+	eq          *equalizer
+	eqGains     [len(EqualizerBands)]float64
+	ctrl        *beep.Ctrl
+	seq         *sequencer
+	sampleRate  beep.SampleRate
+	crossfade   time.Duration
+	replayGain  string
+	length      time.Duration
+	currentSong Audio
+	// title of the song played by live stream
+	streamTitle string
+	// lowers the volume for FadeOut, the volume set by the user is kept
+	fader *effects.Volume
+
+	// song played after the current song, see SetNext
+	next Audio
+	// live stream being opened by Run and the function cancelling it
+	opening    Audio
+	cancelOpen context.CancelFunc
+	// guards the fields above, it is locked before the output
+	mu sync.Mutex
+
+	subscribers []*subscriber
+	subMu       sync.Mutex
+
+	// latest samples sent to the output for Spectrum
+	ring sampleRing
 }
 
 // New returns new Player instance.
@@ -47,105 +74,233 @@ func New(volume int) *Player {
 		initVol = 0
 	}
 
-	return &Player{volume: initVol}
+	return &Player{
+		volume:     initVol,
+		sampleRate: beep.SampleRate(48000),
+		replayGain: ReplayGainOff,
+		speed:      1,
+		output:     &SpeakerOutput{},
+	}
 }
 
-// SetSongFinish accepts callback which will be executed when the song finishes.
-func (p *Player) SetSongFinish(f func(Audio)) {
-	p.songFinish = f
-}
+// SetOutput sets where the samples are played, it must be called before the
+// first song is played.
+func (p *Player) SetOutput(o Output) error {
 
-// SetSongStart accepts callback which will be executed when the song starts.
-func (p *Player) SetSongStart(f func(Audio)) {
-	p.songStart = f
-}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.hasInit {
+		return tracerr.New("output cannot be changed after playback has started")
+	}
+
+	p.output = o
 
-// SetSongSkip accepts callback which will be executed when the song is skipped.
-func (p *Player) SetSongSkip(f func(Audio)) {
-	p.songSkip = f
+	return nil
 }
 
-// executes songFinish callback.
-func (p *Player) execSongFinish(a Audio) {
-	if p.songFinish != nil {
-		p.songFinish(a)
+// Close stops the output and closes the channels of the subscribers. Nothing
+// can be played afterwards.
+func (p *Player) Close() error {
+
+	p.closeSubscribers()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cancelOpening()
+
+	if !p.hasInit {
+		return nil
 	}
+
+	return tracerr.Wrap(p.output.Close())
 }
 
-// executes songStart callback.
-func (p *Player) execSongStart(a Audio) {
-	if p.songStart != nil {
-		p.songStart(a)
+// SetNext sets the song that will be played after the current song, or nil if
+// there is none. The song is opened ahead of time so that it can be played
+// without gap, a different song opened before is dropped right away as long as
+// it has not started, and the new song is opened in its place.
+func (p *Player) SetNext(next Audio) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	changed := p.next != next
+	p.next = next
+
+	if p.seq == nil {
+		return
+	}
+
+	p.output.Lock()
+	defer p.output.Unlock()
+
+	if p.seq.next != nil && p.seq.next.audio != next {
+		p.seq.next.close()
+		p.seq.next = nil
+		changed = true
 	}
-}
 
-// executes songFinish callback.
-func (p *Player) execSongSkip(a Audio) {
-	if p.songSkip != nil {
-		p.songSkip(a)
+	// the new song is requested again once the current song is about to end
+	if changed {
+		p.seq.requested = false
 	}
 }
 
-// Run plays the passed Audio.
+// Run plays the passed Audio, replacing the current song. The replaced song is
+// reported as stopped. Live stream is opened in the background so a slow
+// server never blocks the caller, EventError is published if it can't be
+// played. Stop, Skip and the next Run cancel the stream being opened.
 func (p *Player) Run(currSong Audio) error {
 
-	p.isRunning = true
-	p.execSongStart(currSong)
+	if IsStream(currSong.Path()) {
+		p.runStream(currSong)
+		return nil
+	}
+
+	p.mu.Lock()
+	sr := p.sampleRate
+	replayGain := p.replayGain
+	p.mu.Unlock()
 
-	f, err := os.Open(currSong.Path())
+	// resample to adapt to sample rate of new songs
+	t, err := p.openTrack(context.Background(), currSong, sr, replayGain)
 	if err != nil {
 		return tracerr.Wrap(err)
 	}
 
-	stream, format, err := mp3.Decode(f)
-	if err != nil {
-		return tracerr.Wrap(err)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cancelOpening()
+
+	return p.play(t)
+}
+
+// runStream stops the current song and opens the live stream in its own
+// goroutine.
+func (p *Player) runStream(stream Audio) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cancelOpening()
+
+	// nothing is left playing while waiting for the server
+	if pos, ok := p.stop(); ok {
+		p.publish(Event{Type: EventStopped, Audio: p.currentSong, Position: pos})
 	}
 
-	p.streamSeekCloser = stream
+	ctx, cancel := context.WithCancel(context.Background())
+	p.opening = stream
+	p.cancelOpen = cancel
+	sr := p.sampleRate
+	replayGain := p.replayGain
 
-	// song duration
-	p.length = format.SampleRate.D(p.streamSeekCloser.Len())
+	go func() {
 
-	sr := beep.SampleRate(48000)
-	if !p.hasInit {
+		t, err := p.openTrack(ctx, stream, sr, replayGain)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		// cancelled by Stop, Skip or the next Run in the meantime
+		if ctx.Err() != nil {
+			if t != nil {
+				t.close()
+			}
+			return
+		}
+
+		p.opening = nil
+		p.cancelOpen = nil
 
-		// p.mu.Lock()
-		err := speaker.Init(sr, sr.N(time.Second/10))
-		// p.mu.Unlock()
+		if err == nil {
+			err = p.play(t)
+		}
 
 		if err != nil {
+			cancel()
+			p.publish(Event{Type: EventError, Audio: stream, Err: tracerr.Wrap(err)})
+		}
+	}()
+}
+
+// cancelOpening cancels the live stream being opened by Run, returns the
+// stream or nil if there is none. It must be called with p.mu held.
+func (p *Player) cancelOpening() Audio {
+
+	if p.cancelOpen == nil {
+		return nil
+	}
+
+	p.cancelOpen()
+	stream := p.opening
+	p.opening = nil
+	p.cancelOpen = nil
+
+	return stream
+}
+
+// play starts playing the opened track in place of the current song. It must
+// be called with p.mu held.
+func (p *Player) play(t *track) error {
+
+	sr := p.sampleRate
+
+	if !p.hasInit {
+
+		err := p.output.Init(sr, sr.N(time.Second/10))
+		if err != nil {
+			t.close()
 			return tracerr.Wrap(err)
 		}
 
 		p.hasInit = true
 	}
 
-	p.currentSong = currSong
+	if p.seq != nil {
+		// the paused ctrl would keep streaming silence to the output
+		if pos, ok := p.stop(); ok {
+			p.publish(Event{Type: EventStopped, Audio: p.currentSong, Position: pos})
+		}
+		// drops the song opened ahead by the previous run
+		p.output.Lock()
+		p.seq.close()
+		p.output.Unlock()
+	}
 
-	// resample to adapt to sample rate of new songs
-	resampled := beep.Resample(4, format.SampleRate, sr, p.streamSeekCloser)
+	p.currentSong = t.audio
+	// song duration
+	p.length = t.length()
 
-	sstreamer := beep.Seq(resampled, beep.Callback(func() {
-		p.isRunning = false
-		p.format = nil
-		p.streamSeekCloser.Close()
-		go p.execSongFinish(currSong)
-	}))
+	seq := &sequencer{
+		current:    t,
+		sampleRate: sr,
+		fade:       p.crossfade,
+		preload:    p.preload,
+	}
+	seq.change = func(prev, next *track) {
+		p.change(seq, prev, next)
+	}
+	seq.finish = func(prev *track) {
+		p.finish(seq, prev)
+	}
 
 	ctrl := &beep.Ctrl{
-		Streamer: sstreamer,
+		Streamer: seq,
 		Paused:   false,
 	}
 
-	p.mu.Lock()
-	p.format = &format
+	p.seq = seq
 	p.ctrl = ctrl
-	p.mu.Unlock()
-	resampler := beep.ResampleRatio(4, 1, ctrl)
+	// speed is changed by resampling the song
+	p.resampler = beep.ResampleRatio(4, p.speed, ctrl)
+	p.eq = newEqualizer(p.resampler, sr, p.eqGains)
 
 	volume := &effects.Volume{
-		Streamer: resampler,
+		Streamer: p.eq,
 		Base:     2,
 		Volume:   0,
 		Silent:   false,
@@ -154,148 +309,588 @@ func (p *Player) Run(currSong Audio) error {
 	// sets the volume of previous player
 	volume.Volume += p.volume
 	p.vol = volume
+	p.fader = &effects.Volume{Streamer: volume, Base: 2}
 
-	// starts playing the audio
-	speaker.Play(p.vol)
+	// starts playing the audio, limiter prevents the gain from clipping
+	p.output.Play(&tap{Streamer: newLimiter(p.fader, sr), ring: &p.ring})
+
+	p.state = Playing
+	p.publish(Event{Type: EventStarted, Audio: t.audio})
+	p.setStreamTitle(t)
 
 	return nil
 }
 
+// openTrack opens the track and reports the title changes of live stream.
+func (p *Player) openTrack(
+	ctx context.Context, audio Audio, sr beep.SampleRate, replayGainMode string,
+) (*track, error) {
+
+	t, err := openTrack(ctx, audio, sr, replayGainMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.live != nil {
+		t.live.setTitleFunc(func(title string) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.output.Lock()
+			current := p.seq != nil && p.seq.current == t
+			p.output.Unlock()
+			// title of the preloaded track is reported once it is played
+			if current {
+				p.setStreamTitle(t)
+			}
+		})
+	}
+
+	return t, nil
+}
+
+// setStreamTitle publishes the title of live stream which has just become
+// the current track. It must be called with p.mu held.
+func (p *Player) setStreamTitle(t *track) {
+
+	p.streamTitle = ""
+	if t.live == nil {
+		return
+	}
+
+	p.streamTitle = t.live.getTitle()
+	if p.streamTitle == "" {
+		return
+	}
+
+	p.publish(Event{Type: EventTitle, Audio: t.audio, Title: p.streamTitle})
+}
+
+// preload opens the next song ahead of time and hands it to the sequencer.
+func (p *Player) preload(seq *sequencer) {
+
+	p.mu.Lock()
+	next := p.next
+	sr := p.sampleRate
+	replayGain := p.replayGain
+	p.mu.Unlock()
+
+	if next == nil {
+		return
+	}
+
+	t, err := p.openTrack(context.Background(), next, sr, replayGain)
+	if err != nil {
+		// the next song will be reported when it is played
+		return
+	}
+
+	p.mu.Lock()
+	p.output.Lock()
+	// sequencer could be replaced, the song has already ended or the next
+	// song has been changed in the meantime
+	if p.seq != seq || seq.current == nil || seq.next != nil || p.next != next {
+		p.output.Unlock()
+		p.mu.Unlock()
+		t.close()
+		return
+	}
+	seq.next = t
+	p.output.Unlock()
+	p.mu.Unlock()
+}
+
+// change is executed when the sequencer moves on to the next song.
+func (p *Player) change(seq *sequencer, prev, next *track) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// the sequencer has been replaced in the meantime
+	if p.seq != seq {
+		return
+	}
+
+	p.currentSong = next.audio
+	p.length = next.length()
+	// the next song has been taken, the following song is set by SetNext. A
+	// track opened ahead in the meantime was meant for the song just taken.
+	p.next = nil
+	p.output.Lock()
+	if seq.next != nil {
+		seq.next.close()
+		seq.next = nil
+		seq.requested = false
+	}
+	p.output.Unlock()
+
+	p.publish(Event{Type: EventChanged, Audio: next.audio, Prev: prev.audio})
+	p.publish(Event{Type: EventStarted, Audio: next.audio})
+	p.setStreamTitle(next)
+}
+
+// finish is executed when the sequencer runs out of songs.
+func (p *Player) finish(seq *sequencer, prev *track) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// the song has been skipped or replaced in the meantime
+	if p.seq != seq || p.state == Stopped {
+		return
+	}
+
+	p.state = Stopped
+	p.publish(Event{
+		Type:     EventFinished,
+		Audio:    prev.audio,
+		Position: prev.length(),
+	})
+}
+
+// SetCrossfade sets the duration of crossfade between songs, zero disables
+// crossfade.
+func (p *Player) SetCrossfade(d time.Duration) {
+
+	if d < 0 {
+		d = 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.crossfade = d
+	if p.seq == nil {
+		return
+	}
+
+	p.output.Lock()
+	p.seq.fade = d
+	p.output.Unlock()
+}
+
+// SetReplayGain sets the ReplayGain mode which is one of "off", "track" or
+// "album".
+func (p *Player) SetReplayGain(mode string) error {
+
+	if !ValidReplayGainMode(mode) {
+		return tracerr.Errorf("invalid replaygain mode: %s", mode)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.replayGain = mode
+	if p.seq == nil {
+		return nil
+	}
+
+	p.output.Lock()
+	p.seq.setReplayGain(mode)
+	p.output.Unlock()
+
+	return nil
+}
+
+// SetEqualizer sets the gain in dB of each equalizer band, see
+// EqualizerBands for the frequency of the bands.
+func (p *Player) SetEqualizer(gains []float64) error {
+
+	g, err := EqualizerGains(gains)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.eqGains = g
+	if p.eq == nil {
+		return nil
+	}
+
+	p.output.Lock()
+	p.eq.setGains(g)
+	p.output.Unlock()
+
+	return nil
+}
+
+// GetEqualizer returns the gain in dB of each equalizer band.
+func (p *Player) GetEqualizer() []float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]float64(nil), p.eqGains[:]...)
+}
+
+// SetSpeed sets the playback speed where 1 is the normal speed. The speed is
+// clamped between MinSpeed and MaxSpeed and the result is returned.
+func (p *Player) SetSpeed(ratio float64) float64 {
+
+	ratio = math.Max(MinSpeed, math.Min(MaxSpeed, ratio))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.speed = ratio
+	if p.resampler == nil {
+		return ratio
+	}
+
+	p.output.Lock()
+	p.resampler.SetRatio(ratio)
+	p.output.Unlock()
+
+	return ratio
+}
+
+// GetSpeed returns the current playback speed.
+func (p *Player) GetSpeed() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.speed
+}
+
 // Pause pauses Player.
 func (p *Player) Pause() {
-	speaker.Lock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.state != Playing {
+		return
+	}
+
+	p.output.Lock()
 	p.ctrl.Paused = true
-	p.isRunning = false
-	speaker.Unlock()
+	p.setOutputPaused(true)
+	p.output.Unlock()
+
+	p.state = Paused
+	p.publish(Event{Type: EventPaused, Audio: p.currentSong})
 }
 
 // Play unpauses Player.
 func (p *Player) Play() {
-	speaker.Lock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.state != Paused {
+		return
+	}
+
+	p.output.Lock()
 	p.ctrl.Paused = false
-	p.isRunning = true
-	speaker.Unlock()
+	p.setOutputPaused(false)
+	p.output.Unlock()
+
+	p.state = Playing
+	p.publish(Event{Type: EventResumed, Audio: p.currentSong})
 }
 
 // SetVolume set volume up and volume down using -0.5 or +0.5.
 func (p *Player) SetVolume(v float64) float64 {
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	// check if no songs playing currently
 	if p.vol == nil {
 		p.volume += v
-		return p.volume
+	} else {
+		p.output.Lock()
+		p.vol.Volume += v
+		p.volume = p.vol.Volume
+		p.output.Unlock()
 	}
 
-	speaker.Lock()
-	p.vol.Volume += v
-	p.volume = p.vol.Volume
-	speaker.Unlock()
+	p.publish(Event{Type: EventVolume, Audio: p.currentSong, Volume: p.volume})
+
 	return p.volume
 }
 
-// TogglePause toggles the pause state.
-func (p *Player) TogglePause() {
+// FadeOut lowers the volume to silence over d and pauses the player. The
+// volume is restored afterwards so the next play starts at the volume set by
+// the user, including changes made during the fade. It blocks until the player
+// is paused.
+func (p *Player) FadeOut(d time.Duration) {
+
+	const steps = 50
 
-	if p.ctrl == nil {
+	p.mu.Lock()
+	if p.state != Playing {
+		p.mu.Unlock()
 		return
 	}
+	fader := p.fader
+	p.mu.Unlock()
 
-	if p.ctrl.Paused {
-		p.Play()
-	} else {
+	for i := 1; i < steps; i++ {
+		time.Sleep(d / steps)
+		// linear fade in amplitude
+		p.output.Lock()
+		fader.Volume = math.Log2(1 - float64(i)/steps)
+		p.output.Unlock()
+	}
+
+	time.Sleep(d / steps)
+
+	p.Pause()
+
+	p.output.Lock()
+	fader.Volume = 0
+	p.output.Unlock()
+}
+
+// TogglePause toggles the pause state.
+func (p *Player) TogglePause() {
+	switch p.State() {
+	case Playing:
 		p.Pause()
+	case Paused:
+		p.Play()
 	}
 }
 
-// Skip current song.
+// Skip current song. The song is reported as skipped and finished, so is the
+// live stream being opened.
 func (p *Player) Skip() {
 
-	p.execSongSkip(p.currentSong)
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	if p.currentSong == nil {
+	if stream := p.cancelOpening(); stream != nil {
+		p.publish(Event{Type: EventSkipped, Audio: stream})
+		p.publish(Event{Type: EventFinished, Audio: stream})
 		return
 	}
 
-	// drain the stream
-	speaker.Lock()
+	pos, ok := p.stop()
+	if !ok {
+		return
+	}
+
+	p.publish(Event{Type: EventSkipped, Audio: p.currentSong, Position: pos})
+	p.publish(Event{Type: EventFinished, Audio: p.currentSong, Position: pos})
+}
+
+// Stop stops current song or the live stream being opened. Unlike Skip, the
+// song is not reported as finished so nothing is played afterwards.
+func (p *Player) Stop() {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if stream := p.cancelOpening(); stream != nil {
+		p.publish(Event{Type: EventStopped, Audio: stream})
+		return
+	}
+
+	pos, ok := p.stop()
+	if !ok {
+		return
+	}
+
+	p.publish(Event{Type: EventStopped, Audio: p.currentSong, Position: pos})
+}
+
+// stop drains the stream and returns the position where the song was left
+// off, returns false if there is nothing to stop. It must be called with p.mu
+// held.
+func (p *Player) stop() (time.Duration, bool) {
+
+	if p.state == Stopped {
+		return 0, false
+	}
+
+	p.output.Lock()
+	pos := p.position()
 	p.ctrl.Streamer = nil
-	p.streamSeekCloser.Close()
-	p.isRunning = false
-	p.format = nil
-	speaker.Unlock()
+	p.seq.close()
+	// the drained ctrl is only removed while the output consumes samples
+	p.setOutputPaused(false)
+	p.output.Unlock()
+
+	p.state = Stopped
 
-	p.execSongFinish(p.currentSong)
+	return pos, true
+}
+
+// setOutputPaused tells the output whether the player is paused, see pauser.
+// It must be called with the output locked.
+func (p *Player) setOutputPaused(paused bool) {
+	if o, ok := p.output.(pauser); ok {
+		o.setPaused(paused)
+	}
 }
 
-// GetPosition returns the current position of audio file.
+// GetPosition returns the current position of audio file. The position is
+// measured in the time of the song so it is not affected by the speed.
 func (p *Player) GetPosition() time.Duration {
 
 	p.mu.Lock()
-	speaker.Lock()
-	defer speaker.Unlock()
+	p.output.Lock()
+	defer p.output.Unlock()
 	defer p.mu.Unlock()
-	if p.format == nil || p.streamSeekCloser == nil {
+	if p.seq == nil || p.seq.current == nil {
 		return 1
 	}
 
-	return p.format.SampleRate.D(p.streamSeekCloser.Position())
+	return p.position()
+}
+
+// position returns the position of the current song. It must be called with
+// p.mu and the output locked.
+func (p *Player) position() time.Duration {
+	if p.seq == nil || p.seq.current == nil {
+		return 0
+	}
+	current := p.seq.current
+	return current.format.SampleRate.D(current.stream.Position())
 }
 
 // Seek is the function to move forward and rewind
 func (p *Player) Seek(pos int) error {
 	p.mu.Lock()
-	speaker.Lock()
-	defer speaker.Unlock()
+	p.output.Lock()
+	defer p.output.Unlock()
 	defer p.mu.Unlock()
-	err := p.streamSeekCloser.Seek(pos * int(p.format.SampleRate))
-	return err
+	if p.seq == nil || p.seq.current == nil {
+		return nil
+	}
+
+	// seeking during crossfade ends the crossfade
+	p.seq.dropTail()
+
+	current := p.seq.current
+	err := current.stream.Seek(pos * int(current.format.SampleRate))
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	p.publish(Event{
+		Type:     EventSeeked,
+		Audio:    p.currentSong,
+		Position: time.Duration(pos) * time.Second,
+	})
+
+	return nil
 }
 
-// IsPaused is used to distinguish the player between pause and stop
-func (p *Player) IsPaused() bool {
+// SetLoop repeats the section of the current song between a and b until the
+// loop is cleared or the song changes.
+func (p *Player) SetLoop(a, b time.Duration) error {
+	p.mu.Lock()
+	p.output.Lock()
+	defer p.output.Unlock()
+	defer p.mu.Unlock()
+	if p.seq == nil || p.seq.current == nil {
+		return tracerr.New("no song is playing")
+	}
+
+	// the loop may start before the crossfade ends
+	p.seq.dropTail()
+
+	current := p.seq.current
+	sr := current.format.SampleRate
+	err := current.loop.set(sr.N(a), sr.N(b))
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// ClearLoop stops repeating the section of the current song.
+func (p *Player) ClearLoop() {
+	p.mu.Lock()
+	p.output.Lock()
+	defer p.output.Unlock()
+	defer p.mu.Unlock()
+	if p.seq == nil || p.seq.current == nil {
+		return
+	}
+
+	p.seq.current.loop.clear()
+}
+
+// GetLoop returns the section of the current song being repeated, ok is false
+// if there is none.
+func (p *Player) GetLoop() (a, b time.Duration, ok bool) {
 	p.mu.Lock()
-	speaker.Lock()
-	defer speaker.Unlock()
+	p.output.Lock()
+	defer p.output.Unlock()
 	defer p.mu.Unlock()
-	if p.ctrl == nil {
-		return false
+	if p.seq == nil || p.seq.current == nil {
+		return 0, 0, false
+	}
+
+	current := p.seq.current
+	if !current.loop.active() {
+		return 0, 0, false
 	}
 
-	return p.ctrl.Paused
+	sr := current.format.SampleRate
+	return sr.D(current.loop.start), sr.D(current.loop.end), true
+}
+
+// State returns the playback state of the player.
+func (p *Player) State() State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+// IsPaused is used to distinguish the player between pause and stop
+func (p *Player) IsPaused() bool {
+	return p.State() == Paused
 }
 
 // GetVolume returns current volume.
 func (p *Player) GetVolume() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.volume
 }
 
+// GetLength returns the duration of the current song.
+func (p *Player) GetLength() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.length
+}
+
 // GetCurrentSong returns current song.
 func (p *Player) GetCurrentSong() Audio {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.currentSong
 }
 
+// GetStreamTitle returns the title of the song played by live stream, it is
+// empty if the current song is not a live stream or the title is unknown.
+func (p *Player) GetStreamTitle() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.streamTitle
+}
+
 // HasInit checks if the speaker has been initialized or not. Speaker
 // initialization will only happen once.
 func (p *Player) HasInit() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.hasInit
 }
 
 // IsRunning returns true if Player is running an audio.
 func (p *Player) IsRunning() bool {
-	return p.isRunning
+	return p.State() == Playing
 }
 
 // GetLength return the length of the song in the queue
 func GetLength(audioPath string) (time.Duration, error) {
-	f, err := os.Open(audioPath)
-
-	if err != nil {
-		return 0, tracerr.Wrap(err)
-	}
-
-	defer f.Close()
-
-	streamer, format, err := mp3.Decode(f)
+	streamer, format, err := Decode(audioPath)
 
 	if err != nil {
 		return 0, tracerr.Wrap(err)
@@ -0,0 +1,20 @@
+package player
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlayerSetSpeed(t *testing.T) {
+
+	p := New(80)
+	assert.Equal(t, 1.0, p.GetSpeed())
+
+	assert.Equal(t, 1.5, p.SetSpeed(1.5))
+	assert.Equal(t, 1.5, p.GetSpeed())
+
+	assert.Equal(t, MaxSpeed, p.SetSpeed(3))
+	assert.Equal(t, MinSpeed, p.SetSpeed(0.1))
+	assert.Equal(t, MinSpeed, p.GetSpeed())
+}
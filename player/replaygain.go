@@ -0,0 +1,178 @@
+// Copyright (C) 2020  Raziman
+
+package player
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/faiface/beep"
+	"github.com/tramhao/id3v2"
+	"github.com/ztrue/tracerr"
+)
+
+// ReplayGain modes
+const (
+	ReplayGainOff   = "off"
+	ReplayGainTrack = "track"
+	ReplayGainAlbum = "album"
+)
+
+// TXXX descriptions of ReplayGain frames
+const (
+	TrackGainTag = "REPLAYGAIN_TRACK_GAIN"
+	TrackPeakTag = "REPLAYGAIN_TRACK_PEAK"
+	AlbumGainTag = "REPLAYGAIN_ALBUM_GAIN"
+	AlbumPeakTag = "REPLAYGAIN_ALBUM_PEAK"
+)
+
+// ReplayGain holds the ReplayGain values read from tag. Gains are in dB and
+// peaks are linear sample values where 1 is full scale.
+type ReplayGain struct {
+	TrackGain float64
+	TrackPeak float64
+	AlbumGain float64
+	AlbumPeak float64
+	HasTrack  bool
+	HasAlbum  bool
+}
+
+// ReadReplayGain reads ReplayGain TXXX frames of the audio file.
+func ReadReplayGain(audioPath string) (ReplayGain, error) {
+
+	var rg ReplayGain
+
+	tag, err := id3v2.Open(audioPath, id3v2.Options{Parse: true})
+	if err != nil {
+		return rg, tracerr.Wrap(err)
+	}
+	defer tag.Close()
+
+	frames := tag.GetFrames(tag.CommonID("User defined text information frame"))
+
+	for _, f := range frames {
+
+		udtf, ok := f.(id3v2.UserDefinedTextFrame)
+		if !ok {
+			continue
+		}
+
+		value := strings.TrimSpace(udtf.Value)
+
+		switch strings.ToUpper(udtf.Description) {
+		case TrackGainTag:
+			rg.TrackGain, err = parseGain(value)
+			rg.HasTrack = err == nil
+		case TrackPeakTag:
+			rg.TrackPeak, _ = strconv.ParseFloat(value, 64)
+		case AlbumGainTag:
+			rg.AlbumGain, err = parseGain(value)
+			rg.HasAlbum = err == nil
+		case AlbumPeakTag:
+			rg.AlbumPeak, _ = strconv.ParseFloat(value, 64)
+		}
+	}
+
+	return rg, nil
+}
+
+// parseGain parses gain value in the form of "-6.54 dB".
+func parseGain(value string) (float64, error) {
+
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && strings.EqualFold(value[len(value)-2:], "db") {
+		value = strings.TrimSpace(value[:len(value)-2])
+	}
+
+	gain, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, tracerr.Wrap(err)
+	}
+
+	return gain, nil
+}
+
+// Volume returns the volume in base 10 for effects.Volume of the given mode.
+// Album mode falls back to track gain if the album gain is missing. The gain
+// is reduced when the peak would clip.
+func (rg ReplayGain) Volume(mode string) float64 {
+
+	var gain, peak float64
+
+	switch {
+	case mode == ReplayGainAlbum && rg.HasAlbum:
+		gain, peak = rg.AlbumGain, rg.AlbumPeak
+	case mode == ReplayGainAlbum || mode == ReplayGainTrack:
+		if !rg.HasTrack {
+			return 0
+		}
+		gain, peak = rg.TrackGain, rg.TrackPeak
+	default:
+		return 0
+	}
+
+	// effects.Volume multiplies the samples by 10^volume
+	volume := gain / 20
+
+	if peak > 0 {
+		volume = math.Min(volume, -math.Log10(peak))
+	}
+
+	return volume
+}
+
+// ValidReplayGainMode checks if mode is one of the ReplayGain modes.
+func ValidReplayGainMode(mode string) bool {
+	switch mode {
+	case ReplayGainOff, ReplayGainTrack, ReplayGainAlbum:
+		return true
+	}
+	return false
+}
+
+// limiter is a peak limiter which reduces the gain instantly when a sample
+// exceeds the threshold and recovers slowly, so the output never clips.
+type limiter struct {
+	Streamer  beep.Streamer
+	threshold float64
+	// current gain and the recovery factor per sample
+	gain    float64
+	release float64
+}
+
+// newLimiter returns limiter with release time of 50ms.
+func newLimiter(s beep.Streamer, sr beep.SampleRate) *limiter {
+	return &limiter{
+		Streamer:  s,
+		threshold: 0.98,
+		gain:      1,
+		release:   1 - math.Exp(-1/(0.05*float64(sr))),
+	}
+}
+
+// Stream implements beep.Streamer.
+func (l *limiter) Stream(samples [][2]float64) (n int, ok bool) {
+
+	n, ok = l.Streamer.Stream(samples)
+
+	for i := range samples[:n] {
+
+		peak := math.Max(math.Abs(samples[i][0]), math.Abs(samples[i][1]))
+		if peak*l.gain > l.threshold {
+			l.gain = l.threshold / peak
+		}
+
+		samples[i][0] *= l.gain
+		samples[i][1] *= l.gain
+
+		l.gain += (1 - l.gain) * l.release
+	}
+
+	return n, ok
+}
+
+// Err implements beep.Streamer.
+func (l *limiter) Err() error {
+	return l.Streamer.Err()
+}
@@ -0,0 +1,136 @@
+package player
+
+import (
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+	"github.com/tramhao/id3v2"
+)
+
+func TestParseGain(t *testing.T) {
+
+	samples := map[string]float64{
+		"-6.54 dB": -6.54,
+		"+2.10 dB": 2.1,
+		"0.5db":    0.5,
+		"-1":       -1,
+	}
+
+	for k, v := range samples {
+		got, err := parseGain(k)
+		assert.NoError(t, err, k)
+		assert.InDelta(t, v, got, 0.0001, k)
+	}
+
+	_, err := parseGain("loud")
+	assert.Error(t, err)
+}
+
+func TestReplayGainVolume(t *testing.T) {
+
+	rg := ReplayGain{
+		TrackGain: -6,
+		TrackPeak: 0.9,
+		AlbumGain: -4,
+		AlbumPeak: 0.95,
+		HasTrack:  true,
+		HasAlbum:  true,
+	}
+
+	assert.InDelta(t, -0.3, rg.Volume(ReplayGainTrack), 0.0001)
+	assert.InDelta(t, -0.2, rg.Volume(ReplayGainAlbum), 0.0001)
+	assert.Equal(t, 0.0, rg.Volume(ReplayGainOff))
+
+	// album mode falls back to track gain
+	rg.HasAlbum = false
+	assert.InDelta(t, -0.3, rg.Volume(ReplayGainAlbum), 0.0001)
+
+	// positive gain is reduced so that the peak does not clip
+	rg = ReplayGain{TrackGain: 12, TrackPeak: 0.5, HasTrack: true}
+	assert.InDelta(t, 1.0, 0.5*pow10(rg.Volume(ReplayGainTrack)), 0.0001)
+}
+
+func TestReadReplayGain(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "audio.mp3")
+	copyFile(t, "../test/rap/audio_test.mp3", path)
+
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for desc, value := range map[string]string{
+		TrackGainTag: "-7.25 dB",
+		TrackPeakTag: "0.988",
+		AlbumGainTag: "-6.50 dB",
+	} {
+		tag.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{
+			Encoding:    id3v2.EncodingUTF8,
+			Description: desc,
+			Value:       value,
+		})
+	}
+
+	if err := tag.Save(); err != nil {
+		t.Fatal(err)
+	}
+	tag.Close()
+
+	rg, err := ReadReplayGain(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, rg.HasTrack)
+	assert.True(t, rg.HasAlbum)
+	assert.InDelta(t, -7.25, rg.TrackGain, 0.0001)
+	assert.InDelta(t, 0.988, rg.TrackPeak, 0.0001)
+	assert.InDelta(t, -6.5, rg.AlbumGain, 0.0001)
+}
+
+func TestLimiter(t *testing.T) {
+
+	loud := beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		for i := range samples {
+			samples[i] = [2]float64{1.5, -2}
+		}
+		return len(samples), true
+	})
+
+	l := newLimiter(loud, 48000)
+	samples := make([][2]float64, 512)
+	l.Stream(samples)
+
+	for _, s := range samples {
+		assert.LessOrEqual(t, s[0], 1.0)
+		assert.GreaterOrEqual(t, s[1], -1.0)
+	}
+}
+
+func pow10(x float64) float64 {
+	return math.Pow(10, x)
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	in, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		t.Fatal(err)
+	}
+}
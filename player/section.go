@@ -0,0 +1,72 @@
+// Copyright (C) 2020  Raziman
+
+package player
+
+import (
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/ztrue/tracerr"
+)
+
+// bounded is Audio which is only a part of the file, such as a track of cue
+// sheet.
+type bounded interface {
+	Bounds() (start, end time.Duration, ok bool)
+}
+
+// section plays the part of the stream between start and end. Positions are
+// relative to the start, so the section looks like a whole stream to the rest
+// of the player.
+type section struct {
+	beep.StreamSeekCloser
+	start, end int
+}
+
+// newSection seeks the stream to start, zero end is the end of the stream.
+func newSection(s beep.StreamSeekCloser, start, end int) (*section, error) {
+
+	if end <= 0 || end > s.Len() {
+		end = s.Len()
+	}
+
+	if start < 0 || start >= end {
+		return nil, tracerr.Errorf("invalid section %d-%d", start, end)
+	}
+
+	if err := s.Seek(start); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	return &section{StreamSeekCloser: s, start: start, end: end}, nil
+}
+
+// Stream implements beep.Streamer.
+func (s *section) Stream(samples [][2]float64) (n int, ok bool) {
+
+	left := s.end - s.StreamSeekCloser.Position()
+	if left <= 0 {
+		return 0, false
+	}
+
+	if len(samples) > left {
+		samples = samples[:left]
+	}
+
+	return s.StreamSeekCloser.Stream(samples)
+}
+
+// Len implements beep.StreamSeeker.
+func (s *section) Len() int {
+	return s.end - s.start
+}
+
+// Position implements beep.StreamSeeker.
+func (s *section) Position() int {
+	return s.StreamSeekCloser.Position() - s.start
+}
+
+// Seek implements beep.StreamSeeker.
+func (s *section) Seek(p int) error {
+	return s.StreamSeekCloser.Seek(s.start + p)
+}
@@ -0,0 +1,64 @@
+package player
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSection(t *testing.T) {
+
+	_, err := newSection(nopCloser{newRamp(100)}, 50, 20)
+	assert.Error(t, err)
+
+	s, err := newSection(nopCloser{newRamp(100)}, 20, 30)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, s.Len())
+	assert.Equal(t, 0, s.Position())
+
+	samples := make([][2]float64, 16)
+	n, ok := s.Stream(samples)
+	assert.Equal(t, 10, n)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.02, samples[0][0], 0.0001)
+	assert.InDelta(t, 0.029, samples[9][0], 0.0001)
+
+	// stops at the end of the section
+	_, ok = s.Stream(samples)
+	assert.False(t, ok)
+
+	assert.NoError(t, s.Seek(5))
+	assert.Equal(t, 5, s.Position())
+	s.Stream(samples[:1])
+	assert.InDelta(t, 0.025, samples[0][0], 0.0001)
+
+	// zero end is the end of the stream
+	s, err = newSection(nopCloser{newRamp(100)}, 90, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, s.Len())
+}
+
+func TestPlayerSection(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "silence.wav")
+	writeWav(t, path)
+
+	audio := &AudioFile{path: path, isAudioFile: true}
+	audio.SetBounds(250*time.Millisecond, 750*time.Millisecond)
+
+	p := New(80)
+	assert.NoError(t, p.SetOutput(NewNullOutput()))
+	t.Cleanup(func() { p.Close() })
+	events := p.Subscribe()
+
+	assert.NoError(t, p.Run(audio))
+	assert.Equal(t, 500*time.Millisecond, p.GetLength())
+
+	assert.Equal(t, EventStarted, nextEvent(t, events).Type)
+
+	e := nextEvent(t, events)
+	assert.Equal(t, EventFinished, e.Type)
+	assert.Equal(t, 500*time.Millisecond, e.Position)
+}
@@ -0,0 +1,290 @@
+// Copyright (C) 2020  Raziman
+
+package player
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/effects"
+)
+
+// how long before the end of the current song the next song is opened
+const preloadBefore = 10 * time.Second
+
+// track is a decoded audio which is ready to be streamed to the speaker.
+type track struct {
+	audio  Audio
+	stream beep.StreamSeekCloser
+	format beep.Format
+	// repeats a section of the stream
+	loop *looper
+	// ReplayGain of the audio applied to the stream
+	replayGain ReplayGain
+	gain       *effects.Volume
+	// stream resampled to the sample rate of the speaker
+	streamer beep.Streamer
+	// set when the audio is a live stream
+	live *liveStream
+}
+
+// openTrack decodes the audio, applies the ReplayGain of the given mode and
+// resamples it to sr. ctx aborts opening live stream.
+func openTrack(
+	ctx context.Context, audio Audio, sr beep.SampleRate, replayGainMode string,
+) (*track, error) {
+
+	var stream beep.StreamSeekCloser
+	var format beep.Format
+	var live *liveStream
+	var rg ReplayGain
+	var err error
+
+	if IsStream(audio.Path()) {
+		live, format, err = openStream(ctx, audio.Path())
+		stream = live
+	} else {
+		stream, format, err = Decode(audio.Path())
+		// missing tag simply means no gain adjustment
+		rg, _ = ReadReplayGain(audio.Path())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if b, ok := audio.(bounded); ok && live == nil {
+		if start, end, ok := b.Bounds(); ok {
+			sec, err := newSection(
+				stream, format.SampleRate.N(start), format.SampleRate.N(end))
+			if err != nil {
+				stream.Close()
+				return nil, err
+			}
+			stream = sec
+		}
+	}
+
+	loop := &looper{Streamer: stream}
+
+	gain := &effects.Volume{
+		Streamer: loop,
+		Base:     10,
+		Volume:   rg.Volume(replayGainMode),
+	}
+
+	t := &track{
+		audio:      audio,
+		stream:     stream,
+		format:     format,
+		loop:       loop,
+		replayGain: rg,
+		gain:       gain,
+		streamer:   beep.Resample(4, format.SampleRate, sr, gain),
+		live:       live,
+	}
+
+	return t, nil
+}
+
+// setReplayGain changes the ReplayGain mode of the track.
+func (t *track) setReplayGain(mode string) {
+	t.gain.Volume = t.replayGain.Volume(mode)
+}
+
+// length returns the duration of the whole track, zero for live stream.
+func (t *track) length() time.Duration {
+	return t.format.SampleRate.D(t.stream.Len())
+}
+
+// remaining returns the duration left to be played, live stream never comes
+// to an end.
+func (t *track) remaining() time.Duration {
+	if t.live != nil {
+		return math.MaxInt64
+	}
+	return t.format.SampleRate.D(t.stream.Len() - t.stream.Position())
+}
+
+func (t *track) close() {
+	t.stream.Close()
+}
+
+// sequencer streams the current track and splices the next track right after
+// it ends, so the songs are played back without any gap in between. When fade
+// is set, the tail of the current track is mixed with the head of the next
+// track instead. All of its fields must be accessed while holding the speaker
+// lock.
+type sequencer struct {
+	current *track
+	next    *track
+	// true once the next track has been requested for the current track
+	requested bool
+
+	sampleRate beep.SampleRate
+	// crossfade duration, zero disables crossfade
+	fade time.Duration
+	// the previous track which is fading out
+	tail *track
+	// fade progress and length in samples of the speaker
+	fadePos int
+	fadeLen int
+	buf     [][2]float64
+
+	// preload is called when the current track is about to end
+	preload func(s *sequencer)
+	// change is called when the next track takes over the current track
+	change func(prev, next *track)
+	// finish is called when the current track ends and there is no next track
+	finish func(prev *track)
+}
+
+// Stream implements beep.Streamer.
+func (s *sequencer) Stream(samples [][2]float64) (n int, ok bool) {
+
+	s.startFade()
+
+	for n < len(samples) {
+
+		if s.current == nil {
+			return n, n > 0
+		}
+
+		sn, sok := s.current.streamer.Stream(samples[n:])
+		s.mixTail(samples[n : n+sn])
+		n += sn
+
+		if sok && sn > 0 {
+			continue
+		}
+
+		s.dropTail()
+
+		prev := s.current
+		prev.close()
+
+		s.current = s.next
+		s.next = nil
+		s.requested = false
+
+		// callbacks must not block the speaker
+		if s.current == nil {
+			go s.finish(prev)
+		} else {
+			go s.change(prev, s.current)
+		}
+	}
+
+	if !s.requested && s.current != nil && !s.current.loop.active() &&
+		s.current.remaining() <= preloadBefore+s.fade {
+		s.requested = true
+		go s.preload(s)
+	}
+
+	return n, true
+}
+
+// startFade starts fading out the current track into the next track once the
+// current track is about to end.
+func (s *sequencer) startFade() {
+
+	if s.fade <= 0 || s.tail != nil || s.current == nil || s.next == nil {
+		return
+	}
+
+	// the current track never ends while repeating a section
+	if s.current.loop.active() {
+		return
+	}
+
+	remaining := s.current.remaining()
+	if remaining > s.fade {
+		return
+	}
+
+	s.tail = s.current
+	s.current = s.next
+	s.next = nil
+	s.requested = false
+	s.fadePos = 0
+	s.fadeLen = s.sampleRate.N(remaining)
+
+	// the next song is considered started once the crossfade begins
+	go s.change(s.tail, s.current)
+}
+
+// mixTail ramps up the gain of the current track in samples and mixes the
+// fading out tail into it.
+func (s *sequencer) mixTail(samples [][2]float64) {
+
+	if s.tail == nil {
+		return
+	}
+
+	if len(s.buf) < len(samples) {
+		s.buf = make([][2]float64, len(samples))
+	}
+
+	tn, tok := s.tail.streamer.Stream(s.buf[:len(samples)])
+
+	for i := range samples {
+
+		if s.fadePos >= s.fadeLen {
+			break
+		}
+
+		// equal power crossfade keeps the loudness steady
+		x := float64(s.fadePos) / float64(s.fadeLen) * math.Pi / 2
+		gainIn, gainOut := math.Sin(x), math.Cos(x)
+
+		for c := range samples[i] {
+			samples[i][c] *= gainIn
+			if i < tn {
+				samples[i][c] += s.buf[i][c] * gainOut
+			}
+		}
+
+		s.fadePos++
+	}
+
+	if !tok || s.fadePos >= s.fadeLen {
+		s.dropTail()
+	}
+}
+
+// dropTail stops the crossfade immediately.
+func (s *sequencer) dropTail() {
+	if s.tail != nil {
+		s.tail.close()
+		s.tail = nil
+	}
+}
+
+// Err implements beep.Streamer.
+func (s *sequencer) Err() error {
+	return nil
+}
+
+// setReplayGain changes the ReplayGain mode of all of the tracks.
+func (s *sequencer) setReplayGain(mode string) {
+	for _, t := range []*track{s.current, s.next, s.tail} {
+		if t != nil {
+			t.setReplayGain(mode)
+		}
+	}
+}
+
+// close closes all of the tracks.
+func (s *sequencer) close() {
+	s.dropTail()
+	if s.current != nil {
+		s.current.close()
+		s.current = nil
+	}
+	if s.next != nil {
+		s.next.close()
+		s.next = nil
+	}
+}
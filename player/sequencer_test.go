@@ -0,0 +1,169 @@
+package player
+
+import (
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+)
+
+type testAudio string
+
+func (a testAudio) Name() string { return string(a) }
+func (a testAudio) Path() string { return string(a) }
+
+type nopCloser struct {
+	beep.StreamSeeker
+}
+
+func (nopCloser) Close() error { return nil }
+
+// creates track of constant samples with the given value
+func newTestTrack(name string, value float64, n int) *track {
+
+	format := beep.Format{SampleRate: 100, NumChannels: 2, Precision: 2}
+	buf := beep.NewBuffer(format)
+	buf.Append(beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		if n == 0 {
+			return 0, false
+		}
+		if len(samples) > n {
+			samples = samples[:n]
+		}
+		for i := range samples {
+			samples[i] = [2]float64{value, value}
+		}
+		n -= len(samples)
+		return len(samples), true
+	}))
+
+	stream := nopCloser{buf.Streamer(0, buf.Len())}
+	loop := &looper{Streamer: stream}
+
+	return &track{
+		audio:    testAudio(name),
+		stream:   stream,
+		format:   format,
+		loop:     loop,
+		streamer: loop,
+	}
+}
+
+func TestSequencerGapless(t *testing.T) {
+
+	changed := make(chan [2]Audio, 1)
+	finished := make(chan Audio, 1)
+
+	seq := &sequencer{
+		current: newTestTrack("first", 0.25, 30),
+		preload: func(*sequencer) {},
+		change: func(prev, next *track) {
+			changed <- [2]Audio{prev.audio, next.audio}
+		},
+		finish: func(prev *track) {
+			finished <- prev.audio
+		},
+	}
+	seq.next = newTestTrack("second", 0.5, 30)
+
+	samples := make([][2]float64, 50)
+	n, ok := seq.Stream(samples)
+
+	assert.Equal(t, 50, n)
+	assert.True(t, ok)
+
+	// the second track starts at the very next sample
+	assert.InDelta(t, 0.25, samples[29][0], 0.001)
+	assert.InDelta(t, 0.5, samples[30][0], 0.001)
+
+	select {
+	case songs := <-changed:
+		assert.Equal(t, testAudio("first"), songs[0])
+		assert.Equal(t, testAudio("second"), songs[1])
+	case <-time.After(time.Second):
+		t.Fatal("change callback was not executed")
+	}
+
+	n, _ = seq.Stream(samples)
+	assert.Equal(t, 10, n)
+
+	select {
+	case song := <-finished:
+		assert.Equal(t, testAudio("second"), song)
+	case <-time.After(time.Second):
+		t.Fatal("finish callback was not executed")
+	}
+
+	_, ok = seq.Stream(samples)
+	assert.False(t, ok)
+}
+
+func TestSequencerPreload(t *testing.T) {
+
+	preloaded := make(chan struct{}, 1)
+
+	seq := &sequencer{
+		// 100 seconds long
+		current: newTestTrack("long", 1, 100*100),
+		preload: func(*sequencer) {
+			preloaded <- struct{}{}
+		},
+	}
+
+	seq.Stream(make([][2]float64, 100))
+	assert.False(t, seq.requested)
+
+	seq.current.stream.Seek(seq.current.stream.Len() - 5*100)
+	seq.Stream(make([][2]float64, 100))
+
+	select {
+	case <-preloaded:
+	case <-time.After(time.Second):
+		t.Fatal("next song was not requested")
+	}
+}
+
+func TestSequencerCrossfade(t *testing.T) {
+
+	changed := make(chan struct{}, 1)
+
+	seq := &sequencer{
+		// 1 second long each
+		current:    newTestTrack("first", 0.5, 100),
+		sampleRate: 100,
+		fade:       500 * time.Millisecond,
+		preload:    func(*sequencer) {},
+		change: func(prev, next *track) {
+			changed <- struct{}{}
+		},
+		finish: func(prev *track) {},
+	}
+	seq.next = newTestTrack("second", 0.5, 100)
+
+	samples := make([][2]float64, 50)
+	seq.Stream(samples)
+	assert.Nil(t, seq.tail)
+
+	// crossfade starts with half a second left on the first track
+	seq.Stream(samples)
+	assert.Nil(t, seq.tail)
+
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("change callback was not executed at the start of crossfade")
+	}
+
+	assert.Equal(t, testAudio("second"), seq.current.audio)
+
+	// equal power crossfade of two equal signals never exceeds sqrt(2)
+	for _, s := range samples {
+		assert.True(t, s[0] >= 0.5-0.001 && s[0] <= 0.5*1.4143, s[0])
+	}
+
+	// the second track continues on its own after the crossfade
+	n, _ := seq.Stream(samples)
+	assert.Equal(t, 50, n)
+	assert.InDelta(t, 0.5, samples[0][0], 0.001)
+}
@@ -0,0 +1,168 @@
+// Copyright (C) 2020  Raziman
+
+package player
+
+import (
+	"math"
+	"math/cmplx"
+	"sync"
+
+	"github.com/faiface/beep"
+)
+
+// number of the latest samples analyzed by Spectrum, it must be a power of two
+const spectrumSize = 2048
+
+// frequency range of the spectrum in Hz
+const (
+	spectrumMinFreq = 40.0
+	spectrumMaxFreq = 16000.0
+)
+
+// level in dB shown as silence
+const spectrumFloor = -60.0
+
+// sampleRing keeps the latest samples sent to the output mixed down to mono.
+type sampleRing struct {
+	mu  sync.Mutex
+	buf [spectrumSize]float64
+	pos int
+}
+
+// write adds the samples overwriting the oldest ones.
+func (r *sampleRing) write(samples [][2]float64) {
+	r.mu.Lock()
+	for _, s := range samples {
+		r.buf[r.pos] = (s[0] + s[1]) / 2
+		r.pos = (r.pos + 1) % spectrumSize
+	}
+	r.mu.Unlock()
+}
+
+// read copies the samples from the oldest to the latest.
+func (r *sampleRing) read(dst []float64) {
+	r.mu.Lock()
+	n := copy(dst, r.buf[r.pos:])
+	copy(dst[n:], r.buf[:r.pos])
+	r.mu.Unlock()
+}
+
+// tap copies the samples going to the output into the ring. Only copying is
+// done here so the speaker is never held up by the analysis.
+type tap struct {
+	Streamer beep.Streamer
+	ring     *sampleRing
+}
+
+// Stream implements beep.Streamer.
+func (t *tap) Stream(samples [][2]float64) (n int, ok bool) {
+	n, ok = t.Streamer.Stream(samples)
+	t.ring.write(samples[:n])
+	return n, ok
+}
+
+// Err implements beep.Streamer.
+func (t *tap) Err() error {
+	return t.Streamer.Err()
+}
+
+// fft computes the discrete fourier transform in place, len(x) must be a power
+// of two.
+func fft(x []complex128) {
+
+	n := len(x)
+
+	// bit reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		w := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			wk := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even := x[start+k]
+				odd := x[start+k+size/2] * wk
+				x[start+k] = even + odd
+				x[start+k+size/2] = even - odd
+				wk *= w
+			}
+		}
+	}
+}
+
+// spectrumOf returns the level of each band between 0 and 1 where 1 is a full
+// scale sine. The bands are spaced logarithmically.
+func spectrumOf(samples []float64, sr beep.SampleRate, bands int) []float64 {
+
+	n := len(samples)
+	x := make([]complex128, n)
+
+	for i, s := range samples {
+		// hann window reduces the leakage between bands
+		w := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+		x[i] = complex(s*w, 0)
+	}
+
+	fft(x)
+
+	binWidth := float64(sr) / float64(n)
+	maxFreq := math.Min(spectrumMaxFreq, float64(sr)/2)
+	levels := make([]float64, bands)
+
+	for b := range levels {
+
+		lo := spectrumMinFreq * math.Pow(maxFreq/spectrumMinFreq, float64(b)/float64(bands))
+		hi := spectrumMinFreq * math.Pow(maxFreq/spectrumMinFreq, float64(b+1)/float64(bands))
+
+		from := int(math.Round(lo / binWidth))
+		to := int(math.Round(hi / binWidth))
+		if to <= from {
+			to = from + 1
+		}
+		if to > n/2 {
+			to = n / 2
+		}
+
+		var peak float64
+		for k := from; k < to; k++ {
+			peak = math.Max(peak, cmplx.Abs(x[k]))
+		}
+
+		// hann window halves the amplitude of a sine
+		amplitude := peak * 4 / float64(n)
+		db := 20 * math.Log10(amplitude)
+
+		levels[b] = math.Max(0, math.Min(1, 1-db/spectrumFloor))
+	}
+
+	return levels
+}
+
+// Spectrum returns the level of each band of the sound being played between
+// 0 and 1. The bands are spaced logarithmically from 40Hz to 16kHz. It returns
+// nil when nothing is playing.
+func (p *Player) Spectrum(bands int) []float64 {
+
+	p.mu.Lock()
+	playing := p.state == Playing
+	sr := p.sampleRate
+	p.mu.Unlock()
+
+	if !playing || bands <= 0 {
+		return nil
+	}
+
+	samples := make([]float64, spectrumSize)
+	p.ring.read(samples)
+
+	return spectrumOf(samples, sr, bands)
+}
@@ -0,0 +1,92 @@
+package player
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/faiface/beep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFFT(t *testing.T) {
+
+	const n = 64
+	x := make([]complex128, n)
+	for i := range x {
+		x[i] = complex(math.Sin(2*math.Pi*8*float64(i)/n), 0)
+	}
+
+	fft(x)
+
+	for k := range x {
+		want := 0.0
+		if k == 8 || k == n-8 {
+			want = n / 2
+		}
+		assert.InDelta(t, want, cmplx.Abs(x[k]), 1e-9, "bin %d", k)
+	}
+}
+
+func TestSpectrumOf(t *testing.T) {
+
+	sr := beep.SampleRate(48000)
+	samples := make([]float64, spectrumSize)
+	for i := range samples {
+		samples[i] = math.Sin(2 * math.Pi * 1000 * float64(i) / float64(sr))
+	}
+
+	levels := spectrumOf(samples, sr, 20)
+	assert.Len(t, levels, 20)
+
+	// band containing 1kHz
+	band := int(20 * math.Log(1000/spectrumMinFreq) / math.Log(spectrumMaxFreq/spectrumMinFreq))
+	assert.InDelta(t, 1, levels[band], 0.05)
+
+	// far away from 1kHz
+	assert.Zero(t, levels[0])
+	assert.Zero(t, levels[19])
+
+	silence := spectrumOf(make([]float64, spectrumSize), sr, 20)
+	for _, level := range silence {
+		assert.Zero(t, level)
+	}
+}
+
+func TestTap(t *testing.T) {
+
+	var ring sampleRing
+	n := 0
+	tp := &tap{
+		Streamer: beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+			for i := range samples {
+				n++
+				samples[i] = [2]float64{float64(n), float64(n)}
+			}
+			return len(samples), true
+		}),
+		ring: &ring,
+	}
+
+	buf := make([][2]float64, 1000)
+	for i := 0; i < 3; i++ {
+		tp.Stream(buf)
+	}
+
+	got := make([]float64, spectrumSize)
+	ring.read(got)
+
+	// from the oldest to the latest sample
+	for i, v := range got {
+		assert.Equal(t, float64(3000-spectrumSize+i+1), v)
+	}
+}
+
+func TestPlayerSpectrum(t *testing.T) {
+
+	p := New(80)
+	assert.Nil(t, p.Spectrum(10))
+
+	p, _, _ = newTestPlayer(t)
+	assert.Len(t, p.Spectrum(10), 10)
+}
@@ -0,0 +1,391 @@
+// Copyright (C) 2020  Raziman
+
+package player
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/ztrue/tracerr"
+)
+
+// how much of a live stream is decoded ahead of playback
+const streamBuffer = 5 * time.Second
+
+// how long the server is waited for to connect and send the first audio
+const streamTimeout = 10 * time.Second
+
+// streamClient gives up on servers which do not answer. The body is read for
+// as long as the stream is played, so it has no overall timeout.
+var streamClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: streamTimeout}).DialContext,
+		TLSHandshakeTimeout:   streamTimeout,
+		ResponseHeaderTimeout: streamTimeout,
+	},
+}
+
+// decoders of the content types sent by streaming servers
+var streamContentTypes = map[string]string{
+	"audio/mpeg":      "mp3",
+	"audio/mp3":       "mp3",
+	"audio/ogg":       "vorbis",
+	"application/ogg": "vorbis",
+	"audio/vorbis":    "vorbis",
+	"audio/flac":      "flac",
+	"audio/x-flac":    "flac",
+	"audio/wav":       "wav",
+	"audio/x-wav":     "wav",
+}
+
+var streamTitleRe = regexp.MustCompile(`StreamTitle='(.*?)';`)
+
+// IsStream checks if the path is url of http audio stream.
+func IsStream(audioPath string) bool {
+	return strings.HasPrefix(audioPath, "http://") ||
+		strings.HasPrefix(audioPath, "https://")
+}
+
+// parseStreamTitle extracts StreamTitle from ICY metadata.
+func parseStreamTitle(metadata string) (string, bool) {
+
+	m := streamTitleRe.FindStringSubmatch(strings.TrimRight(metadata, "\x00"))
+	if m == nil {
+		return "", false
+	}
+
+	return strings.TrimSpace(m[1]), true
+}
+
+// icyReader removes ICY metadata from the stream. Servers insert a metadata
+// block after every metaint bytes of audio when they are asked to.
+type icyReader struct {
+	r       io.Reader
+	metaint int
+	// audio bytes left until the next metadata block
+	remaining int
+	onTitle   func(title string)
+}
+
+// Read implements io.Reader.
+func (r *icyReader) Read(p []byte) (int, error) {
+
+	if r.remaining == 0 {
+		err := r.readMetadata()
+		if err != nil {
+			return 0, err
+		}
+		r.remaining = r.metaint
+	}
+
+	if len(p) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.r.Read(p)
+	r.remaining -= n
+
+	return n, err
+}
+
+// readMetadata reads metadata block which is prefixed by its length divided
+// by 16.
+func (r *icyReader) readMetadata() error {
+
+	var length [1]byte
+	_, err := io.ReadFull(r.r, length[:])
+	if err != nil {
+		return err
+	}
+
+	if length[0] == 0 {
+		return nil
+	}
+
+	metadata := make([]byte, int(length[0])*16)
+	_, err = io.ReadFull(r.r, metadata)
+	if err != nil {
+		return err
+	}
+
+	if title, ok := parseStreamTitle(string(metadata)); ok {
+		r.onTitle(title)
+	}
+
+	return nil
+}
+
+// liveStream is http audio stream which can't be seeked and has no length. It
+// is decoded ahead of playback in its own goroutine so slow network never
+// blocks the speaker, silence is played whenever the buffer runs out.
+type liveStream struct {
+	body io.Closer
+	mu   sync.Mutex
+	// signaled when samples are consumed or the stream is closed
+	cond *sync.Cond
+	buf  [][2]float64
+	// maximum number of samples decoded ahead
+	size int
+	// the decoder has reached the end of the stream
+	done   bool
+	closed bool
+	err    error
+	pos    int
+	// last StreamTitle received
+	title   string
+	onTitle func(title string)
+	// cancels the request of the stream
+	cancel context.CancelFunc
+}
+
+// openStream connects to the stream and starts decoding it. Cancelling ctx
+// aborts the connection, it must not be cancelled while the stream is played.
+func openStream(ctx context.Context, streamURL string) (*liveStream, beep.Format, error) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	// the first audio is waited for with a deadline as well, cancelling the
+	// request interrupts reading the body
+	timer := time.AfterFunc(streamTimeout, cancel)
+
+	fail := func(err error) (*liveStream, beep.Format, error) {
+		timer.Stop()
+		cancel()
+		return nil, beep.Format{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		return fail(tracerr.Wrap(err))
+	}
+
+	// asks for the title of the current song
+	req.Header.Set("Icy-MetaData", "1")
+
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return fail(tracerr.Wrap(err))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return fail(tracerr.Errorf(
+			"unable to open stream %s: %s", streamURL, resp.Status))
+	}
+
+	s := &liveStream{body: resp.Body, cancel: cancel}
+	s.cond = sync.NewCond(&s.mu)
+
+	var r io.Reader = resp.Body
+	metaint, err := strconv.Atoi(resp.Header.Get("icy-metaint"))
+	if err == nil && metaint > 0 {
+		r = &icyReader{
+			r:         r,
+			metaint:   metaint,
+			remaining: metaint,
+			onTitle:   s.setTitle,
+		}
+	}
+
+	br := bufio.NewReader(r)
+	// error is reported by the decoder
+	header, _ := br.Peek(sniffLen)
+
+	d, ok := findStreamDecoder(resp.Header.Get("Content-Type"), streamURL, header)
+	if !ok {
+		resp.Body.Close()
+		return fail(tracerr.Errorf("unsupported audio format: %s", streamURL))
+	}
+
+	rc := struct {
+		io.Reader
+		io.Closer
+	}{br, resp.Body}
+
+	stream, format, err := d.Decode(rc)
+	if err != nil {
+		resp.Body.Close()
+		return fail(tracerr.Wrap(err))
+	}
+
+	// the deadline has passed right as the first audio arrived
+	if !timer.Stop() {
+		stream.Close()
+		return fail(tracerr.Errorf("stream %s timed out", streamURL))
+	}
+
+	s.size = format.SampleRate.N(streamBuffer)
+	go s.decode(stream)
+
+	return s, format, nil
+}
+
+// findStreamDecoder picks decoder by the content type and falls back to the
+// content and the extension of the url.
+func findStreamDecoder(contentType, streamURL string, header []byte) (Decoder, bool) {
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if name, ok := streamContentTypes[mediaType]; ok {
+		for _, d := range decoders {
+			if d.Name == name {
+				return d, true
+			}
+		}
+	}
+
+	urlPath := streamURL
+	if u, err := url.Parse(streamURL); err == nil {
+		urlPath = path.Base(u.Path)
+	}
+
+	return findDecoder(urlPath, header)
+}
+
+// decode fills the buffer until the stream ends or is closed.
+func (s *liveStream) decode(stream beep.StreamSeekCloser) {
+
+	defer stream.Close()
+
+	chunk := make([][2]float64, 512)
+
+	for {
+
+		n, ok := stream.Stream(chunk)
+
+		s.mu.Lock()
+
+		for !s.closed && len(s.buf)+n > s.size {
+			s.cond.Wait()
+		}
+
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+
+		s.buf = append(s.buf, chunk[:n]...)
+
+		if !ok {
+			s.done = true
+			s.err = stream.Err()
+			s.mu.Unlock()
+			return
+		}
+
+		s.mu.Unlock()
+	}
+}
+
+// setTitle is called by the decoder when StreamTitle is received.
+func (s *liveStream) setTitle(title string) {
+
+	s.mu.Lock()
+	if title == s.title {
+		s.mu.Unlock()
+		return
+	}
+	s.title = title
+	onTitle := s.onTitle
+	s.mu.Unlock()
+
+	if onTitle != nil {
+		onTitle(title)
+	}
+}
+
+// setTitleFunc sets callback which is executed when the title changes.
+func (s *liveStream) setTitleFunc(f func(title string)) {
+	s.mu.Lock()
+	s.onTitle = f
+	s.mu.Unlock()
+}
+
+// getTitle returns the last StreamTitle received.
+func (s *liveStream) getTitle() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.title
+}
+
+// Stream implements beep.Streamer.
+func (s *liveStream) Stream(samples [][2]float64) (n int, ok bool) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n = copy(samples, s.buf)
+	s.buf = s.buf[n:]
+	s.cond.Signal()
+
+	if n < len(samples) {
+
+		if s.done {
+			s.pos += n
+			return n, n > 0
+		}
+
+		// waits for the network with silence
+		for i := range samples[n:] {
+			samples[n+i] = [2]float64{}
+		}
+		n = len(samples)
+	}
+
+	s.pos += n
+
+	return n, true
+}
+
+// Err implements beep.Streamer.
+func (s *liveStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Len implements beep.StreamSeeker, live stream has no length.
+func (s *liveStream) Len() int {
+	return 0
+}
+
+// Position implements beep.StreamSeeker.
+func (s *liveStream) Position() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pos
+}
+
+// Seek implements beep.StreamSeeker, live stream can't be seeked.
+func (s *liveStream) Seek(p int) error {
+	return tracerr.New("live stream cannot be seeked")
+}
+
+// Close implements beep.StreamCloser.
+func (s *liveStream) Close() error {
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	// interrupts the decoder waiting for the network
+	err := s.body.Close()
+	s.cancel()
+	return err
+}
@@ -0,0 +1,175 @@
+package player
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// interleaves ICY metadata block with the title after every metaint bytes
+func icyEncode(data []byte, metaint int, title string) []byte {
+
+	metadata := []byte(fmt.Sprintf("StreamTitle='%s';", title))
+	// length of the block is a multiple of 16
+	blocks := (len(metadata) + 15) / 16
+	metadata = append(metadata, make([]byte, blocks*16-len(metadata))...)
+
+	var result bytes.Buffer
+	for len(data) > 0 {
+		n := metaint
+		if n > len(data) {
+			n = len(data)
+		}
+		result.Write(data[:n])
+		data = data[n:]
+
+		if len(data) > 0 {
+			result.WriteByte(byte(blocks))
+			result.Write(metadata)
+		}
+	}
+
+	return result.Bytes()
+}
+
+func TestParseStreamTitle(t *testing.T) {
+
+	tests := []struct {
+		metadata string
+		title    string
+		ok       bool
+	}{
+		{"StreamTitle='Artist - Song';StreamUrl='';\x00\x00", "Artist - Song", true},
+		{"StreamTitle='';", "", true},
+		{"StreamUrl='http://example.com';", "", false},
+	}
+
+	for _, test := range tests {
+		title, ok := parseStreamTitle(test.metadata)
+		assert.Equal(t, test.ok, ok, test.metadata)
+		assert.Equal(t, test.title, title, test.metadata)
+	}
+}
+
+func TestIcyReader(t *testing.T) {
+
+	audio := bytes.Repeat([]byte("0123456789"), 100)
+
+	var titles []string
+	r := &icyReader{
+		r:         bytes.NewReader(icyEncode(audio, 64, "Artist - Song")),
+		metaint:   64,
+		remaining: 64,
+		onTitle: func(title string) {
+			titles = append(titles, title)
+		},
+	}
+
+	got, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, audio, got)
+	assert.Len(t, titles, len(audio)/64)
+	assert.Equal(t, "Artist - Song", titles[0])
+}
+
+func TestPlayerStream(t *testing.T) {
+
+	data, err := os.ReadFile("../test/rap/audio_test.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const metaint = 8192
+
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "audio/mpeg")
+			if r.Header.Get("Icy-MetaData") != "1" {
+				w.Write(data)
+				return
+			}
+			w.Header().Set("icy-metaint", fmt.Sprint(metaint))
+			w.Write(icyEncode(data, metaint, "Artist - Song"))
+		}))
+	defer server.Close()
+
+	assert.True(t, IsStream(server.URL))
+	assert.False(t, IsStream("../test/rap/audio_test.mp3"))
+
+	p := New(80)
+	assert.NoError(t, p.SetOutput(NewNullOutput()))
+	defer p.Close()
+
+	events := p.Subscribe()
+	audio := testAudio(server.URL + "/stream")
+
+	if err := p.Run(audio); err != nil {
+		t.Fatal(err)
+	}
+
+	e := nextEvent(t, events)
+	assert.Equal(t, EventStarted, e.Type)
+
+	e = nextEvent(t, events)
+	assert.Equal(t, EventTitle, e.Type)
+	assert.Equal(t, "Artist - Song", e.Title)
+	assert.Equal(t, audio, e.Audio)
+	assert.Equal(t, "Artist - Song", p.GetStreamTitle())
+
+	// live stream has no length and can't be seeked
+	assert.Zero(t, p.GetLength())
+	assert.Error(t, p.Seek(10))
+
+	p.Skip()
+	e = nextEvent(t, events)
+	assert.Equal(t, EventSkipped, e.Type)
+}
+
+func TestPlayerStreamOpening(t *testing.T) {
+
+	// server which never sends the response
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-unblock:
+			case <-r.Context().Done():
+			}
+		}))
+	defer server.Close()
+	defer close(unblock)
+
+	p := New(80)
+	assert.NoError(t, p.SetOutput(NewNullOutput()))
+	defer p.Close()
+
+	events := p.Subscribe()
+	audio := testAudio(server.URL + "/stream")
+
+	// the stream is opened without blocking and can be stopped meanwhile
+	assert.NoError(t, p.Run(audio))
+	assert.Equal(t, Stopped, p.State())
+
+	p.Stop()
+	e := nextEvent(t, events)
+	assert.Equal(t, EventStopped, e.Type)
+	assert.Equal(t, audio, e.Audio)
+
+	// stream which can't be played is reported by the event
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+
+	audio = testAudio(missing.URL + "/stream")
+	assert.NoError(t, p.Run(audio))
+
+	e = nextEvent(t, events)
+	assert.Equal(t, EventError, e.Type)
+	assert.Equal(t, audio, e.Audio)
+	assert.Error(t, e.Err)
+}
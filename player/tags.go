@@ -0,0 +1,241 @@
+// Copyright (C) 2020  Raziman
+
+package player
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jfreymuth/oggvorbis"
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+	"github.com/tramhao/id3v2"
+	"github.com/ztrue/tracerr"
+)
+
+// Tags are the text tags describing the audio file.
+type Tags struct {
+	Artist string
+	Title  string
+	Album  string
+	// Track is the number of the track in the album, zero if unknown
+	Track int
+}
+
+// TagsFunc reads the tags of audio data from rs, which is at the start of the
+// file.
+type TagsFunc func(rs io.ReadSeeker) (Tags, error)
+
+// ReadTags reads the tags of the audio file using the decoder of its format,
+// the tags are empty if the format has no tags.
+func ReadTags(audioPath string) (Tags, error) {
+
+	f, err := os.Open(audioPath)
+	if err != nil {
+		return Tags{}, tracerr.Wrap(err)
+	}
+	defer f.Close()
+
+	header, err := readHeader(f)
+	if err != nil {
+		return Tags{}, tracerr.Wrap(err)
+	}
+
+	d, ok := findDecoder(audioPath, header)
+	if !ok {
+		return Tags{}, tracerr.Errorf("unsupported audio format: %s", audioPath)
+	}
+
+	if d.Tags == nil {
+		return Tags{}, nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return Tags{}, tracerr.Wrap(err)
+	}
+
+	tags, err := d.Tags(f)
+	if err != nil {
+		return Tags{}, tracerr.Wrap(err)
+	}
+
+	return tags, nil
+}
+
+// parseTrack parses track number in the form of "3" or "3/12".
+func parseTrack(value string) int {
+	if i := strings.IndexByte(value, '/'); i >= 0 {
+		value = value[:i]
+	}
+	track, _ := strconv.Atoi(strings.TrimSpace(value))
+	return track
+}
+
+// id3Tags reads the ID3v2 tag.
+func id3Tags(rs io.ReadSeeker) (Tags, error) {
+
+	trackID := "Track number/Position in set"
+
+	tag, err := id3v2.ParseReader(rs, id3v2.Options{
+		Parse:       true,
+		ParseFrames: []string{"Artist", "Title", "Album", trackID},
+	})
+	if err != nil {
+		return Tags{}, tracerr.Wrap(err)
+	}
+
+	return Tags{
+		Artist: tag.Artist(),
+		Title:  tag.Title(),
+		Album:  tag.Album(),
+		Track:  parseTrack(tag.GetTextFrame(tag.CommonID(trackID)).Text),
+	}, nil
+}
+
+// vorbisTags reads the tags from the fields of vorbis comment.
+func vorbisTags(fields [][2]string) Tags {
+
+	var tags Tags
+
+	for _, field := range fields {
+		value := strings.TrimSpace(field[1])
+		switch strings.ToUpper(field[0]) {
+		case "ARTIST":
+			tags.Artist = value
+		case "TITLE":
+			tags.Title = value
+		case "ALBUM":
+			tags.Album = value
+		case "TRACKNUMBER":
+			tags.Track = parseTrack(value)
+		}
+	}
+
+	return tags
+}
+
+// flacTags reads the vorbis comment block of FLAC.
+func flacTags(rs io.ReadSeeker) (Tags, error) {
+
+	stream, err := flac.Parse(rs)
+	if err != nil {
+		return Tags{}, tracerr.Wrap(err)
+	}
+
+	for _, block := range stream.Blocks {
+		if comment, ok := block.Body.(*meta.VorbisComment); ok {
+			return vorbisTags(comment.Tags), nil
+		}
+	}
+
+	return Tags{}, nil
+}
+
+// oggTags reads the comment header of ogg vorbis.
+func oggTags(rs io.ReadSeeker) (Tags, error) {
+
+	header, err := oggvorbis.GetCommentHeader(rs)
+	if err != nil {
+		return Tags{}, tracerr.Wrap(err)
+	}
+
+	var fields [][2]string
+	for _, comment := range header.Comments {
+		if i := strings.IndexByte(comment, '='); i >= 0 {
+			fields = append(fields, [2]string{comment[:i], comment[i+1:]})
+		}
+	}
+
+	return vorbisTags(fields), nil
+}
+
+// wavTags reads the INFO list of WAV, or the ID3v2 tag stored in its own
+// chunk.
+func wavTags(rs io.ReadSeeker) (Tags, error) {
+
+	// RIFF header
+	if _, err := rs.Seek(12, io.SeekStart); err != nil {
+		return Tags{}, tracerr.Wrap(err)
+	}
+
+	var tags Tags
+
+	for {
+
+		var chunk struct {
+			ID   [4]byte
+			Size uint32
+		}
+		err := binary.Read(rs, binary.LittleEndian, &chunk)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return tags, nil
+		} else if err != nil {
+			return Tags{}, tracerr.Wrap(err)
+		}
+
+		// chunks are padded to even size
+		size := int64(chunk.Size) + int64(chunk.Size%2)
+
+		switch strings.ToLower(string(chunk.ID[:])) {
+		case "list":
+			data := make([]byte, size)
+			if _, err := io.ReadFull(rs, data); err != nil {
+				return Tags{}, tracerr.Wrap(err)
+			}
+			if bytes.HasPrefix(data, []byte("INFO")) {
+				tags = infoTags(data[4:])
+			}
+		case "id3 ":
+			data := make([]byte, size)
+			if _, err := io.ReadFull(rs, data); err != nil {
+				return Tags{}, tracerr.Wrap(err)
+			}
+			return id3Tags(bytes.NewReader(data))
+		default:
+			if _, err := rs.Seek(size, io.SeekCurrent); err != nil {
+				return Tags{}, tracerr.Wrap(err)
+			}
+		}
+	}
+}
+
+// infoTags reads the sub-chunks of INFO list.
+func infoTags(data []byte) Tags {
+
+	var tags Tags
+
+	for len(data) >= 8 {
+
+		id := string(data[:4])
+		size := int(binary.LittleEndian.Uint32(data[4:8]))
+		data = data[8:]
+		if size > len(data) {
+			break
+		}
+
+		value := strings.TrimSpace(strings.TrimRight(string(data[:size]), "\x00"))
+
+		switch id {
+		case "IART":
+			tags.Artist = value
+		case "INAM":
+			tags.Title = value
+		case "IPRD":
+			tags.Album = value
+		case "ITRK", "IPRT":
+			tags.Track = parseTrack(value)
+		}
+
+		size += size % 2
+		if size > len(data) {
+			break
+		}
+		data = data[size:]
+	}
+
+	return tags
+}
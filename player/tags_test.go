@@ -0,0 +1,81 @@
+package player
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// appends INFO list with the sub-chunks to the wav file
+func appendInfo(t *testing.T, path string, chunks map[string]string) {
+
+	var info bytes.Buffer
+	info.WriteString("INFO")
+	for id, value := range chunks {
+		data := append([]byte(value), 0)
+		info.WriteString(id)
+		binary.Write(&info, binary.LittleEndian, uint32(len(data)))
+		info.Write(data)
+		if len(data)%2 == 1 {
+			info.WriteByte(0)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	f.WriteString("LIST")
+	binary.Write(f, binary.LittleEndian, uint32(info.Len()))
+	f.Write(info.Bytes())
+}
+
+func TestReadTags(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "song.wav")
+	writeWav(t, path)
+	appendInfo(t, path, map[string]string{
+		"IART": "Artist",
+		"INAM": "Title",
+		"IPRD": "Album",
+		"ITRK": "3",
+	})
+
+	tags, err := ReadTags(path)
+	assert.NoError(t, err)
+	assert.Equal(t, Tags{Artist: "Artist", Title: "Title", Album: "Album", Track: 3}, tags)
+
+	// wav without INFO list has no tags
+	plain := filepath.Join(t.TempDir(), "plain.wav")
+	writeWav(t, plain)
+
+	tags, err = ReadTags(plain)
+	assert.NoError(t, err)
+	assert.Equal(t, Tags{}, tags)
+}
+
+func TestVorbisTags(t *testing.T) {
+
+	tags := vorbisTags([][2]string{
+		{"artist", "Artist"},
+		{"TITLE", " Title "},
+		{"Album", "Album"},
+		{"TRACKNUMBER", "7/12"},
+		{"GENRE", "Jazz"},
+	})
+
+	assert.Equal(t, Tags{Artist: "Artist", Title: "Title", Album: "Album", Track: 7}, tags)
+}
+
+func TestParseTrack(t *testing.T) {
+	assert.Equal(t, 3, parseTrack("3"))
+	assert.Equal(t, 3, parseTrack(" 3/12"))
+	assert.Equal(t, 0, parseTrack(""))
+	assert.Equal(t, 0, parseTrack("A1"))
+}
@@ -9,6 +9,7 @@ import (
 	"image"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -39,6 +40,18 @@ type PlayingBar struct {
 	albumPhoto       *ugo.Image
 	albumPhotoSource image.Image
 	colrowPixel      int32
+	// point A of the loop in nanoseconds, -1 when it is not set
+	loopStart int64
+	// spectrum is shown in place of album photo when it is not zero
+	visualizer int32
+	// lines of the text drawn by run, the spectrum is drawn between them
+	mu           sync.Mutex
+	progressText string
+	lyricText    string
+	// chapters of the current song and the index of the chapter shown
+	chapters []player.Chapter
+	chapter  int
+	songName string
 }
 
 func (p *PlayingBar) help() []string {
@@ -57,9 +70,11 @@ func newPlayingBar() *PlayingBar {
 	frame.SetBackgroundColor(gomu.colors.background)
 
 	p := &PlayingBar{
-		Frame:  frame,
-		text:   textView,
-		update: make(chan struct{}),
+		Frame:     frame,
+		text:      textView,
+		update:    make(chan struct{}),
+		loopStart: -1,
+		chapter:   -1,
 	}
 
 	return p
@@ -70,11 +85,13 @@ func (p *PlayingBar) run() error {
 
 	for {
 
-		// stop progressing if song ends or skipped
+		// stop progressing if song ends or skipped, live stream has no length
 		progress := p.getProgress()
 		full := p.getFull()
+		live := full == 0
 
-		if progress > full || p.skip {
+		if (!live && progress > full) || p.skip ||
+			gomu.player.State() == player.Stopped {
 			p.skip = false
 			p.setProgress(0)
 			break
@@ -85,8 +102,10 @@ func (p *PlayingBar) run() error {
 			continue
 		}
 
-		// p.progress = int(gomu.player.GetPosition().Seconds())
-		p.setProgress(int(gomu.player.GetPosition().Seconds()))
+		// position is in the time of the song, so it stays correct at any
+		// playback speed
+		progress = int(gomu.player.GetPosition().Seconds())
+		p.setProgress(progress)
 
 		start, err := time.ParseDuration(strconv.Itoa(progress) + "s")
 		if err != nil {
@@ -98,6 +117,11 @@ func (p *PlayingBar) run() error {
 		if err != nil {
 			return tracerr.Wrap(err)
 		}
+
+		endText := fmtDuration(end)
+		if live {
+			endText = "live"
+		}
 		var width, colrowPixel int
 		gomu.app.QueueUpdate(func() {
 			_, _, width, _ = p.GetInnerRect()
@@ -107,7 +131,15 @@ func (p *PlayingBar) run() error {
 			colrowPixel = rowPixel + colPixel
 		})
 
-		progressBar := progresStr(progress, full, width/2, "█", "━")
+		var progressBar string
+		if live {
+			progressBar = strings.Repeat("━", width/2)
+		} else {
+			progressBar = progresStr(progress, full, width/2, "█", "━")
+			progressBar = p.markChapters(progressBar, full)
+			progressBar = p.markLoop(progressBar, full)
+			p.updateChapter(gomu.player.GetPosition())
+		}
 		if p.getColRowPixel() != colrowPixel {
 			p.updatePhoto()
 			p.setColRowPixel(colrowPixel)
@@ -121,22 +153,70 @@ func (p *PlayingBar) run() error {
 			}
 		}
 
-		gomu.app.QueueUpdateDraw(func() {
-			p.text.SetText(fmt.Sprintf("%s ┃%s┫ %s\n\n[%s]%v[-]",
-				fmtDuration(start),
-				progressBar,
-				fmtDuration(end),
-				gomu.colors.subtitle,
-				lyricText,
-			))
-		})
+		speed := gomu.player.GetSpeed()
+		var speedText string
+		if speed != 1 {
+			speedText = fmt.Sprintf(" %.1fx", speed)
+		}
+
+		p.setText(
+			fmt.Sprintf("%s ┃%s┫ %s%s",
+				fmtDuration(start), progressBar, endText, speedText),
+			fmt.Sprintf("[%s]%v[-]", gomu.colors.subtitle, lyricText),
+		)
 
-		<-time.After(time.Second)
+		// updates every second of the song regardless of the speed
+		<-time.After(time.Duration(float64(time.Second) / speed))
 	}
 
 	return nil
 }
 
+// Marks point A and point B of the loop on the progress bar
+func (p *PlayingBar) markLoop(progressBar string, full int) string {
+
+	var points []time.Duration
+	var labels []string
+
+	if a, b, ok := gomu.player.GetLoop(); ok {
+		points = []time.Duration{a, b}
+		labels = []string{"A", "B"}
+	} else if a, ok := p.getLoopStart(); ok {
+		points = []time.Duration{a}
+		labels = []string{"A"}
+	}
+
+	if len(points) == 0 || full <= 0 {
+		return progressBar
+	}
+
+	bar := []rune(progressBar)
+	marked := make(map[int]string)
+	for i, point := range points {
+		index := int(point.Seconds()) * len(bar) / full
+		if index >= len(bar) {
+			index = len(bar) - 1
+		}
+		if index >= 0 {
+			marked[index] = labels[i]
+		}
+	}
+
+	r, g, b := gomu.colors.accent.RGB()
+	hexColor := padHex(r, g, b)
+
+	var result strings.Builder
+	for i, c := range bar {
+		if label, ok := marked[i]; ok {
+			fmt.Fprintf(&result, "[#%s]%s[-]", hexColor, label)
+		} else {
+			result.WriteRune(c)
+		}
+	}
+
+	return result.String()
+}
+
 // Updates song title
 func (p *PlayingBar) setSongTitle(title string) {
 	p.Clear()
@@ -149,21 +229,45 @@ func (p *PlayingBar) setSongTitle(title string) {
 func (p *PlayingBar) newProgress(currentSong *player.AudioFile, full int) {
 	p.setFull(full)
 	p.setProgress(0)
+	// loop belongs to the previous song
+	p.clearLoopStart()
 	p.hasTag = false
 	p.tag = nil
 	p.subtitles = nil
 	p.subtitle = nil
+	p.songName = currentSong.Name()
+	p.setChapters(nil)
 	if p.albumPhoto != nil {
 		p.albumPhoto.Clear()
 		p.albumPhoto.Destroy()
 		p.albumPhoto = nil
 	}
 
+	// live stream has no tag
+	if player.IsStream(currentSong.Path()) {
+		p.setSongTitle(currentSong.Name())
+		return
+	}
+
 	err := p.loadLyrics(currentSong.Path())
 	if err != nil {
 		errorPopup(err)
 		return
 	}
+
+	// timing of the lyrics and chapters is relative to the whole file
+	if isCueTrack(currentSong) {
+		p.subtitles = nil
+		p.setSongTitle(currentSong.Name())
+		return
+	}
+
+	chapters, err := currentSong.LoadChapters()
+	if err != nil {
+		logError(err)
+	}
+	p.setChapters(chapters)
+
 	langLyricFromConfig := gomu.anko.GetString("General.lang_lyric")
 	if langLyricFromConfig == "" {
 		langLyricFromConfig = "en"
@@ -361,6 +465,19 @@ func (p *PlayingBar) setFull(full int) {
 	atomic.StoreInt32(&p.full, int32(full))
 }
 
+func (p *PlayingBar) getLoopStart() (time.Duration, bool) {
+	start := atomic.LoadInt64(&p.loopStart)
+	return time.Duration(start), start >= 0
+}
+
+func (p *PlayingBar) setLoopStart(start time.Duration) {
+	atomic.StoreInt64(&p.loopStart, int64(start))
+}
+
+func (p *PlayingBar) clearLoopStart() {
+	atomic.StoreInt64(&p.loopStart, -1)
+}
+
 func (p *PlayingBar) getColRowPixel() int {
 	return int(atomic.LoadInt32(&p.colrowPixel))
 }
@@ -384,6 +501,12 @@ func getConsoleSize() (int, int, int, int) {
 // updatePhoto finish two tasks: 1. resize photo based on room left for photo
 // 2. register photo in the correct position
 func (p *PlayingBar) updatePhoto() {
+
+	// the room is taken by the visualizer
+	if p.isVisualizerOn() {
+		return
+	}
+
 	// Put the whole block in goroutine, in order not to block the whole apps
 	// also to avoid data race by adding QueueUpdateDraw
 	go gomu.app.QueueUpdateDraw(func() {
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/issadarkthing/gomu/player"
 )
@@ -57,3 +59,55 @@ func Test_Stop(t *testing.T) {
 		t.Errorf("Expected %t; got %t", true, p.skip)
 	}
 }
+
+func Test_MarkLoop(t *testing.T) {
+
+	gomu = newGomu()
+	err := execConfig(expandFilePath(testConfigPath))
+	if err != nil {
+		t.Error(err)
+	}
+
+	gomu.colors = newColor()
+	gomu.player = player.New(0)
+
+	p := newPlayingBar()
+	bar := "██████━━━━"
+
+	// nothing to mark
+	if got := p.markLoop(bar, 100); got != bar {
+		t.Errorf("Expected %s; got %s", bar, got)
+	}
+
+	p.setLoopStart(30 * time.Second)
+	got := p.markLoop(bar, 100)
+
+	r, g, b := gomu.colors.accent.RGB()
+	expected := fmt.Sprintf("███[#%s]A[-]██━━━━", padHex(r, g, b))
+	if got != expected {
+		t.Errorf("Expected %s; got %s", expected, got)
+	}
+
+	// point A is cleared on new song
+	audio := new(player.AudioFile)
+	audio.SetPath("./test/rap/audio_test.mp3")
+	p.newProgress(audio, 100)
+
+	if _, ok := p.getLoopStart(); ok {
+		t.Errorf("Expected loop start to be cleared")
+	}
+}
+
+func TestMarkChapters(t *testing.T) {
+
+	p := &PlayingBar{}
+	p.setChapters([]player.Chapter{
+		{Start: 0},
+		{Start: 50 * time.Second},
+	})
+
+	got := p.markChapters("██████████", 100)
+	if got != "█████╋████" {
+		t.Errorf("expected chapter tick in the middle, got %s", got)
+	}
+}
@@ -39,6 +39,9 @@ type Playlist struct {
 	download int
 	done     chan struct{}
 	yankFile *player.AudioFile
+	// progress of loudness scan
+	scanned   int32
+	scanTotal int32
 }
 
 func (p *Playlist) help() []string {
@@ -50,6 +53,10 @@ func (p *Playlist) help() []string {
 		"a      create a playlist",
 		"l      add song to queue",
 		"L      add playlist to queue",
+		"i      play song next",
+		"I      play playlist next",
+		"o      play song now",
+		"O      play playlist now",
 		"d      delete file from filesystem",
 		"D      delete playlist from filesystem",
 		"Y      download audio from url",
@@ -123,6 +130,11 @@ func newPlaylist(args Args) *Playlist {
 
 	populate(root, rootDir, gomu.anko.GetBool("General.sort_by_mtime"))
 
+	err = addStations(root, stationsPath())
+	if err != nil {
+		logError(err)
+	}
+
 	var firstChild *tview.TreeNode
 
 	if len(root.GetChildren()) == 0 {
@@ -149,6 +161,10 @@ func newPlaylist(args Args) *Playlist {
 		's': "youtube_search",
 		'l': "add_queue",
 		'L': "bulk_add",
+		'i': "play_next",
+		'I': "bulk_play_next",
+		'o': "play_now",
+		'O': "bulk_play_now",
 		'h': "close_node",
 		'r': "refresh",
 		'R': "rename",
@@ -292,6 +308,11 @@ func (p *Playlist) refresh() {
 
 	populate(root, node.Path(), gomu.anko.GetBool("General.sort_by_mtime"))
 
+	err := addStations(root, stationsPath())
+	if err != nil {
+		logError(err)
+	}
+
 	root.Walk(func(node, _ *tview.TreeNode) bool {
 
 		// to preserve previously highlighted node
@@ -439,10 +460,10 @@ func (p *Playlist) rename(newName string) error {
 	currentNode := p.GetCurrentNode()
 	audio := currentNode.GetReference().(*player.AudioFile)
 
-	pathToFile, _ := filepath.Split(audio.Path())
+	pathToFile, fileName := filepath.Split(audio.Path())
 	var newPath string
 	if audio.IsAudioFile() {
-		newPath = pathToFile + newName + ".mp3"
+		newPath = pathToFile + newName + filepath.Ext(fileName)
 	} else {
 		newPath = pathToFile + newName
 	}
@@ -644,6 +665,28 @@ func populate(root *tview.TreeNode, rootPath string, sortMtime bool) error {
 		})
 	}
 
+	// audio files split by cue sheets are shown as their tracks instead
+	sheets := make(map[string]cueSheet)
+	splitFiles := make(map[string]bool)
+
+	for _, file := range files {
+
+		if !file.Mode().IsRegular() || !isCueSheet(file.Name()) {
+			continue
+		}
+
+		sheet, err := loadCue(filepath.Join(rootPath, file.Name()))
+		if err != nil {
+			logError(err)
+			continue
+		}
+
+		sheets[file.Name()] = sheet
+		for _, track := range sheet.tracks {
+			splitFiles[track.file] = true
+		}
+	}
+
 	for _, file := range files {
 
 		path, err := filepath.EvalSymlinks(filepath.Join(rootPath, file.Name()))
@@ -654,22 +697,27 @@ func populate(root *tview.TreeNode, rootPath string, sortMtime bool) error {
 		songName := getName(file.Name())
 		child := tview.NewTreeNode(songName)
 
-		if file.Mode().IsRegular() {
+		if sheet, ok := sheets[file.Name()]; ok {
+			addCueTracks(root, sheet)
+			continue
+		}
 
-			f, err := os.Open(path)
+		if file.Mode().IsRegular() && isPlaylistFile(path) {
+			err := addPlaylistFile(root, path)
 			if err != nil {
-				continue
+				logError(err)
 			}
-			defer f.Close()
+			continue
+		}
 
-			filetype, err := getFileContentType(f)
+		if file.Mode().IsRegular() {
 
-			if err != nil {
+			if splitFiles[path] {
 				continue
 			}
 
-			// skip if not mp3 file
-			if filetype != "mpeg" {
+			// skip if none of the decoders is able to play it
+			if !player.IsSupported(path) {
 				continue
 			}
 
@@ -727,6 +775,18 @@ func (p *Playlist) yank() error {
 	if p.yankFile.Node() == p.GetRoot() {
 		return errors.New("please don't yank the root directory")
 	}
+	if isStation(p.yankFile) {
+		p.yankFile = nil
+		return errors.New("stations can only be changed in the stations file")
+	}
+	if isCueTrack(p.yankFile) {
+		p.yankFile = nil
+		return errors.New("tracks of cue sheet can only be moved with their file")
+	}
+	if inPlaylistFile(p.yankFile) {
+		p.yankFile = nil
+		return errors.New("playlist files can only be changed in the file")
+	}
 	defaultTimedPopup(" Success ", p.yankFile.Name()+"\n has been yanked successfully.")
 
 	return nil
@@ -740,6 +800,12 @@ func (p *Playlist) paste() error {
 	oldAudio := p.yankFile
 	oldPathDir, oldPathFileName := filepath.Split(p.yankFile.Path())
 	pasteFile := p.getCurrentFile()
+	if isStation(pasteFile) {
+		return errors.New("unable to paste into the stations")
+	}
+	if inPlaylistFile(pasteFile) {
+		return errors.New("unable to paste into playlist file")
+	}
 	var newPathDir string
 	if pasteFile.IsAudioFile() {
 		newPathDir, _ = filepath.Split(pasteFile.Path())
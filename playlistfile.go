@@ -0,0 +1,410 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/tview"
+	"github.com/ztrue/tracerr"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// playlistEntry is a song listed in playlist file, title is empty when the
+// file does not provide one
+type playlistEntry struct {
+	path  string
+	title string
+}
+
+// Checks if the file is a playlist file, eg. m3u, m3u8 or pls
+func isPlaylistFile(fileName string) bool {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".m3u", ".m3u8", ".pls":
+		return true
+	}
+	return false
+}
+
+// Checks if the audio file is a playlist file or a song listed in it. The
+// songs live elsewhere, so they must not be changed through the playlist.
+func inPlaylistFile(audioFile *player.AudioFile) bool {
+
+	if audioFile == nil {
+		return false
+	}
+
+	if !audioFile.IsAudioFile() {
+		return isPlaylistFile(audioFile.Path())
+	}
+
+	parent := audioFile.Parent()
+	return parent != nil && !parent.IsAudioFile() && isPlaylistFile(parent.Path())
+}
+
+// Parses m3u and m3u8 playlist, the title comes from #EXTINF line
+func parseM3U(r io.Reader) ([]playlistEntry, error) {
+
+	var entries []playlistEntry
+	var title string
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+
+		switch {
+		case line == "":
+			continue
+
+		// #EXTINF:duration,title
+		case strings.HasPrefix(line, "#EXTINF:"):
+			if i := strings.Index(line, ","); i >= 0 {
+				title = strings.TrimSpace(line[i+1:])
+			}
+
+		case strings.HasPrefix(line, "#"):
+			continue
+
+		default:
+			entries = append(entries, playlistEntry{path: line, title: title})
+			title = ""
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	return entries, nil
+}
+
+// Parses pls playlist, entries are numbered by FileN and TitleN keys
+func parsePLS(r io.Reader) ([]playlistEntry, error) {
+
+	files := make(map[int]string)
+	titles := make(map[int]string)
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+
+		i := strings.Index(line, "=")
+		if i < 0 {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+
+		var entries map[int]string
+		switch {
+		case strings.HasPrefix(key, "file"):
+			entries = files
+			key = strings.TrimPrefix(key, "file")
+		case strings.HasPrefix(key, "title"):
+			entries = titles
+			key = strings.TrimPrefix(key, "title")
+		default:
+			continue
+		}
+
+		n, err := strconv.Atoi(key)
+		if err != nil {
+			continue
+		}
+		entries[n] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	numbers := make([]int, 0, len(files))
+	for n := range files {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	entries := make([]playlistEntry, 0, len(numbers))
+	for _, n := range numbers {
+		entries = append(entries, playlistEntry{path: files[n], title: titles[n]})
+	}
+
+	return entries, nil
+}
+
+// Gets the path of the song listed in playlist, relative paths are relative to
+// the directory of the playlist
+func resolveEntryPath(dir, entryPath string) string {
+
+	if player.IsStream(entryPath) {
+		return entryPath
+	}
+
+	if strings.HasPrefix(entryPath, "file://") {
+		if u, err := url.Parse(entryPath); err == nil {
+			entryPath = u.Path
+		}
+	}
+
+	// playlists made on windows
+	entryPath = filepath.FromSlash(strings.ReplaceAll(entryPath, `\`, "/"))
+	entryPath = expandTilde(entryPath)
+
+	if !filepath.IsAbs(entryPath) {
+		entryPath = filepath.Join(dir, entryPath)
+	}
+
+	return filepath.Clean(entryPath)
+}
+
+// Reads the songs of playlist file. The entries which do not refer to a
+// playable song are returned as unresolved.
+func readPlaylistFile(playlistPath string) (
+	songs []*player.AudioFile, unresolved []string, err error,
+) {
+
+	f, err := os.Open(playlistPath)
+	if err != nil {
+		return nil, nil, tracerr.Wrap(err)
+	}
+	defer f.Close()
+
+	var entries []playlistEntry
+	if strings.EqualFold(filepath.Ext(playlistPath), ".pls") {
+		entries, err = parsePLS(f)
+	} else {
+		entries, err = parseM3U(f)
+	}
+	if err != nil {
+		return nil, nil, tracerr.Wrap(err)
+	}
+
+	dir := filepath.Dir(playlistPath)
+
+	for _, entry := range entries {
+
+		songPath := resolveEntryPath(dir, entry.path)
+
+		if !player.IsStream(songPath) {
+			resolved, err := filepath.EvalSymlinks(songPath)
+			if err != nil || !player.IsSupported(resolved) {
+				unresolved = append(unresolved, entry.path)
+				continue
+			}
+			songPath = resolved
+		}
+
+		name := entry.title
+		if name == "" {
+			name = getName(songPath)
+		}
+
+		audioFile := new(player.AudioFile)
+		audioFile.SetName(name)
+		audioFile.SetPath(songPath)
+		audioFile.SetIsAudioFile(true)
+
+		if !player.IsStream(songPath) {
+			length, err := getTagLength(songPath)
+			if err != nil {
+				logError(err)
+			}
+			audioFile.SetLen(length)
+		}
+
+		songs = append(songs, audioFile)
+	}
+
+	return songs, unresolved, nil
+}
+
+// Adds playlist file as a playlist of root which lists its songs
+func addPlaylistFile(root *tview.TreeNode, playlistPath string) error {
+
+	songs, unresolved, err := readPlaylistFile(playlistPath)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	name := filepath.Base(playlistPath)
+	node := tview.NewTreeNode(name)
+
+	playlist := new(player.AudioFile)
+	playlist.SetName(name)
+	playlist.SetPath(playlistPath)
+	playlist.SetIsAudioFile(false)
+	playlist.SetNode(node)
+	playlist.SetParentNode(root)
+
+	displayText := setDisplayText(playlist)
+	if len(unresolved) > 0 {
+		displayText += fmt.Sprintf(" (%d unresolved)", len(unresolved))
+		logError(tracerr.Errorf(
+			"unresolved entries in %s: %s",
+			playlistPath, strings.Join(unresolved, ", ")))
+	}
+
+	node.SetReference(playlist)
+	node.SetColor(gomu.colors.playlistDir)
+	node.SetText(displayText)
+	root.AddChild(node)
+
+	for _, song := range songs {
+
+		child := tview.NewTreeNode(song.Name())
+
+		song.SetNode(child)
+		song.SetParentNode(node)
+
+		child.SetReference(song)
+		child.SetText(setDisplayText(song))
+		node.AddChild(child)
+	}
+
+	return nil
+}
+
+// Writes the songs as m3u8 playlist. Paths of songs under the directory of
+// the playlist are written relative to it, so the directory can be moved
+// around. Tracks of cue sheet are written as their whole file.
+func writeM3U8(w io.Writer, playlistPath string, songs []*player.AudioFile) error {
+
+	dir := filepath.Dir(playlistPath)
+	var content strings.Builder
+
+	content.WriteString("#EXTM3U\n")
+
+	for _, song := range songs {
+
+		seconds := -1
+		if length, err := songLength(song); err == nil && length > 0 {
+			seconds = int(length.Seconds())
+		}
+
+		songPath := song.Path()
+		if !player.IsStream(songPath) {
+			rel, err := filepath.Rel(dir, songPath)
+			if err == nil && !strings.HasPrefix(rel, "..") {
+				songPath = rel
+			}
+		}
+
+		fmt.Fprintf(&content, "#EXTINF:%d,%s\n%s\n", seconds, song.Name(), songPath)
+	}
+
+	_, err := io.WriteString(w, content.String())
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// Gets path of the playlist file to be saved, plain name is saved in the
+// music directory
+func playlistFilePath(name string) string {
+
+	name = expandTilde(name)
+
+	if !strings.ContainsRune(name, os.PathSeparator) {
+		musicDir := expandTilde(gomu.anko.GetString("General.music_dir"))
+		name = filepath.Join(musicDir, name)
+	}
+
+	if !isPlaylistFile(name) {
+		name += ".m3u8"
+	}
+
+	return name
+}
+
+// Saves the queue as m3u8 playlist
+func (q *Queue) exportPlaylist(playlistPath string) error {
+
+	var content strings.Builder
+
+	err := writeM3U8(&content, playlistPath, q.items)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(playlistPath), 0744)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	err = ioutil.WriteFile(playlistPath, []byte(content.String()), 0644)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// Adds the songs of playlist file to the queue, returns the entries which
+// could not be added
+func (q *Queue) importPlaylist(playlistPath string) ([]string, error) {
+
+	songs, unresolved, err := readPlaylistFile(playlistPath)
+	if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	for _, song := range songs {
+		_, err := q.enqueue(song)
+		if err != nil {
+			logError(err)
+			unresolved = append(unresolved, song.Path())
+		}
+	}
+
+	return unresolved, nil
+}
+
+// Lists the playlist files in the playlist tree
+func (p *Playlist) playlistFiles() []string {
+
+	var files []string
+
+	p.GetRoot().Walk(func(node, _ *tview.TreeNode) bool {
+		audioFile := node.GetReference().(*player.AudioFile)
+		if !audioFile.IsAudioFile() && isPlaylistFile(audioFile.Path()) {
+			files = append(files, audioFile.Path())
+		}
+		return true
+	})
+
+	return files
+}
+
+// Shows the entries that could not be loaded
+func unresolvedPopup(title string, unresolved []string) {
+
+	const maxShown = 5
+
+	shown := unresolved
+	if len(shown) > maxShown {
+		shown = shown[:maxShown]
+	}
+
+	description := fmt.Sprintf("%d entries could not be found:\n%s",
+		len(unresolved), strings.Join(shown, "\n"))
+	if len(unresolved) > maxShown {
+		description += "\n..."
+	}
+
+	defaultTimedPopup(title, description)
+}
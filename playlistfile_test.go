@@ -0,0 +1,130 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+func TestParseM3U(t *testing.T) {
+
+	const m3u = `#EXTM3U
+#EXTINF:123,Artist - Song
+song.mp3
+
+# comment
+/music/other.flac
+`
+
+	entries, err := parseM3U(strings.NewReader(m3u))
+	assert.NoError(t, err)
+	assert.Equal(t, []playlistEntry{
+		{path: "song.mp3", title: "Artist - Song"},
+		{path: "/music/other.flac"},
+	}, entries)
+}
+
+func TestParsePLS(t *testing.T) {
+
+	const pls = `[playlist]
+File2=http://localhost:8000/stream
+Title2=Radio
+File1=song.mp3
+NumberOfEntries=2
+Version=2
+`
+
+	entries, err := parsePLS(strings.NewReader(pls))
+	assert.NoError(t, err)
+	assert.Equal(t, []playlistEntry{
+		{path: "song.mp3"},
+		{path: "http://localhost:8000/stream", title: "Radio"},
+	}, entries)
+}
+
+func TestResolveEntryPath(t *testing.T) {
+
+	tests := []struct {
+		entry string
+		want  string
+	}{
+		{"song.mp3", "/music/mix/song.mp3"},
+		{"../rock/song.mp3", "/music/rock/song.mp3"},
+		{`..\rock\song.mp3`, "/music/rock/song.mp3"},
+		{"/other/song.mp3", "/other/song.mp3"},
+		{"file:///other/my%20song.mp3", "/other/my song.mp3"},
+		{"http://localhost:8000/stream", "http://localhost:8000/stream"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, resolveEntryPath("/music/mix", test.entry), test.entry)
+	}
+}
+
+func TestPlaylistFileRoundTrip(t *testing.T) {
+
+	dir := t.TempDir()
+	songPath := filepath.Join(dir, "song.mp3")
+	copyTestFile(t, "./test/rap/audio_test.mp3", songPath)
+
+	song := new(player.AudioFile)
+	song.SetName("Artist - Song")
+	song.SetPath(songPath)
+	song.SetIsAudioFile(true)
+
+	playlistPath := filepath.Join(dir, "mix.m3u8")
+
+	var content strings.Builder
+	assert.NoError(t, writeM3U8(&content, playlistPath, []*player.AudioFile{song}))
+	assert.True(t, strings.HasPrefix(content.String(), "#EXTM3U\n#EXTINF:"))
+	assert.True(t, strings.HasSuffix(content.String(), ",Artist - Song\nsong.mp3\n"))
+
+	// the missing song is reported
+	missing := content.String() + "missing.mp3\n"
+	err := ioutil.WriteFile(playlistPath, []byte(missing), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	songs, unresolved, err := readPlaylistFile(playlistPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"missing.mp3"}, unresolved)
+	assert.Len(t, songs, 1)
+	assert.Equal(t, "Artist - Song", songs[0].Name())
+	assert.Equal(t, songPath, songs[0].Path())
+}
+
+func TestImportPlaylist(t *testing.T) {
+
+	prepareEqualizerTest(t)
+	gomu.colors = newColor()
+	gomu = prepareTest()
+
+	dir := t.TempDir()
+	songPath := filepath.Join(dir, "song.mp3")
+	copyTestFile(t, "./test/rap/audio_test.mp3", songPath)
+
+	// song which can't be read is not added
+	brokenPath := filepath.Join(dir, "broken.mp3")
+	err := ioutil.WriteFile(brokenPath, []byte("not audio"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	playlistPath := filepath.Join(dir, "mix.m3u")
+	err = ioutil.WriteFile(playlistPath, []byte("broken.mp3\nsong.mp3\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unresolved, err := gomu.queue.importPlaylist(playlistPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{brokenPath}, unresolved)
+	assert.Equal(t, []string{songPath}, queuePaths())
+	assert.Equal(t, len(gomu.queue.items), gomu.queue.GetItemCount())
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"github.com/rivo/tview"
+	"github.com/ztrue/tracerr"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// Gets the songs of the node to be added at once, the songs of the playlist
+// which contains the node if the node is a song. The song being played is left
+// out like addAllToQueue does.
+func bulkSongs(node *tview.TreeNode) []*player.AudioFile {
+
+	children := node.GetChildren()
+
+	if audioFile := node.GetReference().(*player.AudioFile); audioFile.IsAudioFile() {
+		children = audioFile.ParentNode().GetChildren()
+	}
+
+	var songs []*player.AudioFile
+	currSong := gomu.player.GetCurrentSong()
+
+	for _, child := range children {
+		audioFile := child.GetReference().(*player.AudioFile)
+		if !audioFile.IsAudioFile() {
+			continue
+		}
+		if currSong != nil && audioFile.Name() == currSong.Name() {
+			continue
+		}
+		songs = append(songs, audioFile)
+	}
+
+	return songs
+}
+
+// Inserts the songs to be played after the current song and after the songs
+// which were added the same way before. The queue scrolls to the first song.
+func (q *Queue) playNext(songs []*player.AudioFile) error {
+
+	if len(songs) == 0 {
+		return nil
+	}
+
+	index := q.nextIndex
+	if index > len(q.items) {
+		index = len(q.items)
+	}
+
+	for i, song := range songs {
+		err := q.insertItem(index+i, song)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+	}
+
+	q.nextIndex = index + len(songs)
+	q.SetCurrentItem(index)
+
+	if gomu.player.IsRunning() || gomu.player.IsPaused() {
+		return nil
+	}
+
+	// the first song is taken out of the queue to be played, which moves
+	// nextIndex back by one
+	err := q.playQueue()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// Inserts the songs at the front of the queue and plays the first one right
+// away, the current song is put in the history
+func (q *Queue) playNow(songs []*player.AudioFile) error {
+
+	if len(songs) == 0 {
+		return nil
+	}
+
+	for i, song := range songs {
+		err := q.insertItem(i, song)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+	}
+
+	q.SetCurrentItem(0)
+
+	// the current song is reported as stopped, so it is put in the history
+	err := q.playQueue()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+func TestPlayNext(t *testing.T) {
+
+	prepareEqualizerTest(t)
+	gomu.colors = newColor()
+	gomu = prepareTest()
+	assert.NoError(t, gomu.player.SetOutput(player.NewNullOutput()))
+	defer gomu.player.Close()
+
+	rapDir := gomu.playlist.GetRoot().GetChildren()[1]
+	var songs []*player.AudioFile
+	for _, node := range rapDir.GetChildren() {
+		songs = append(songs, node.GetReference().(*player.AudioFile))
+	}
+	a, b, c := songs[0], songs[1], songs[2]
+
+	_, err := gomu.queue.enqueue(c)
+	assert.NoError(t, err)
+
+	// nothing is playing so the first song is played right away
+	assert.NoError(t, gomu.queue.playNext([]*player.AudioFile{a, b}))
+	assert.Equal(t, a, gomu.player.GetCurrentSong())
+	assert.Equal(t, []*player.AudioFile{b, c}, gomu.queue.items)
+
+	// songs are played in the order they are added
+	assert.NoError(t, gomu.queue.playNext([]*player.AudioFile{a}))
+	assert.Equal(t, []*player.AudioFile{b, a, c}, gomu.queue.items)
+	assert.Equal(t, 1, gomu.queue.GetCurrentItem())
+
+	events := gomu.player.Subscribe()
+
+	assert.NoError(t, gomu.queue.playNow([]*player.AudioFile{c}))
+	assert.Equal(t, c, gomu.player.GetCurrentSong())
+	assert.Equal(t, []*player.AudioFile{b, a, c}, gomu.queue.items)
+
+	// the replaced song is stopped, so it goes to the history
+	e := <-events
+	assert.Equal(t, player.EventStopped, e.Type)
+	assert.Equal(t, a, e.Audio)
+}
+
+func TestInsertItem(t *testing.T) {
+
+	gomu = prepareTest()
+
+	song := gomu.playlist.GetRoot().GetChildren()[1].GetChildren()[0].
+		GetReference().(*player.AudioFile)
+
+	// inserting at the end appends the song
+	assert.NoError(t, gomu.queue.insertItem(0, song))
+	assert.NoError(t, gomu.queue.insertItem(1, song))
+	assert.Error(t, gomu.queue.insertItem(3, song))
+	assert.Equal(t, 2, gomu.queue.GetItemCount())
+	assert.Len(t, gomu.queue.items, 2)
+}
+
+func TestNextIndex(t *testing.T) {
+
+	songs := prepareSelectionTest(t)
+	q := gomu.queue
+
+	// the first two songs were added by play next
+	q.nextIndex = 2
+
+	_, err := q.deleteItem(0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, q.nextIndex)
+
+	// songs after the play next songs do not move them
+	_, err = q.deleteItem(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, q.nextIndex)
+
+	q.pushFront(songs[0])
+	assert.Equal(t, 2, q.nextIndex)
+
+	q.clearQueue()
+	assert.Equal(t, 0, q.nextIndex)
+}
@@ -89,6 +89,23 @@ func getPopupTimeout() time.Duration {
 	return m
 }
 
+// Gets crossfade duration from config file
+func getCrossfade() time.Duration {
+
+	dur := gomu.anko.GetString("General.crossfade")
+	if dur == "" {
+		return 0
+	}
+
+	m, err := time.ParseDuration(dur)
+	if err != nil {
+		logError(err)
+		return 0
+	}
+
+	return m
+}
+
 // Simple confirmation popup. Accepts callback
 func confirmationPopup(
 	text string,
@@ -257,6 +274,11 @@ func volumePopup(volume float64) {
 	defaultTimedPopup(" Volume ", progress)
 }
 
+// Shows popup for the current playback speed
+func speedPopup(speed float64) {
+	defaultTimedPopup(" Speed ", fmt.Sprintf("\n%.1fx", speed))
+}
+
 // Shows a list of keybind. The upper list is the local keybindings to specific
 // panel only. The lower list is the global keybindings
 func helpPopup(panel Panel) {
@@ -269,15 +291,24 @@ func helpPopup(panel Panel) {
 		"space  toggle play/pause",
 		"esc    close popup",
 		"n      skip",
+		"N      previous song or restart the current song",
 		"q      quit",
 		"+      volume up",
 		"-      volume down",
+		">/<    speed up/down",
+		"[/]    set loop point A/B",
+		"|      clear loop",
+		"(/)    previous/next chapter",
+		"C      chapters",
+		"S      sleep timer",
 		"f/F    forward 10/60 seconds",
 		"b/B    rewind 10/60 seconds",
 		"?      toggle help",
 		"m      open repl",
 		"T      switch lyrics",
 		"c      show colors",
+		"e      equalizer",
+		"v      toggle visualizer",
 	}
 
 	list := tview.NewList().ShowSecondaryText(false)
@@ -0,0 +1,205 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ztrue/tracerr"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// positions close to the start or the end of the song are not worth resuming
+const positionMargin = 10 * time.Second
+
+// Positions remembers where the long songs such as podcasts and audiobooks
+// were left off so they continue from there the next time they are played
+type Positions struct {
+	mu   sync.Mutex
+	path string
+	// songs shorter than this are always played from the start, zero
+	// disables resuming
+	threshold time.Duration
+	positions map[string]time.Duration
+	// positions which are resumed only once regardless of the length of the
+	// song, eg. the song playing when gomu exited
+	once map[string]time.Duration
+}
+
+func newPositions(path string) *Positions {
+	return &Positions{
+		path:      path,
+		positions: map[string]time.Duration{},
+		once:      map[string]time.Duration{},
+	}
+}
+
+// Gets path of the positions file
+func positionsPath() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		logError(tracerr.Wrap(err))
+	}
+	return filepath.Join(cacheDir, "gomu", "positions")
+}
+
+// Gets the key of the song in the positions file, tracks of cue sheet share
+// the file so they are told apart by their start
+func positionKey(audio player.Audio) string {
+
+	if audioFile, ok := audio.(*player.AudioFile); ok {
+		if start, _, ok := audioFile.Bounds(); ok {
+			return fmt.Sprintf("%s#%d", audio.Path(), start.Milliseconds())
+		}
+	}
+
+	return audio.Path()
+}
+
+// Gets resume threshold from config file
+func getResumeThreshold() time.Duration {
+
+	dur := gomu.anko.GetString("General.resume_threshold")
+	if dur == "" {
+		return 0
+	}
+
+	m, err := time.ParseDuration(dur)
+	if err != nil {
+		logError(err)
+		return 0
+	}
+
+	return m
+}
+
+// Loads the positions file, each line is the position in seconds followed by
+// the path of the song. Nothing is loaded if the file does not exist.
+func (p *Positions) load() error {
+
+	f, err := os.Open(p.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer f.Close()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+
+		fields := strings.SplitN(scanner.Text(), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		seconds, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+
+		p.positions[fields[1]] = time.Duration(seconds) * time.Second
+	}
+
+	if err := scanner.Err(); err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// Writes the positions file, it must be called with p.mu held
+func (p *Positions) save() error {
+
+	var content strings.Builder
+
+	for songPath, pos := range p.positions {
+		content.WriteString(fmt.Sprintf("%d %s\n", int(pos.Seconds()), songPath))
+	}
+
+	err := os.MkdirAll(filepath.Dir(p.path), 0744)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	err = ioutil.WriteFile(p.path, []byte(content.String()), 0644)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// Remembers the position where the song was left off if the song is long
+// enough. The position is forgotten once the song is played to the end.
+func (p *Positions) remember(songPath string, pos, length time.Duration) error {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if player.IsStream(songPath) || p.threshold <= 0 || length < p.threshold {
+		return nil
+	}
+
+	if pos < positionMargin || pos > length-positionMargin {
+		return p.remove(songPath)
+	}
+
+	p.positions[songPath] = pos
+
+	return p.save()
+}
+
+// Forgets the position of the song so it is played from the start
+func (p *Positions) forget(songPath string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.remove(songPath)
+}
+
+// Removes the position of the song, it must be called with p.mu held
+func (p *Positions) remove(songPath string) error {
+
+	if _, ok := p.positions[songPath]; !ok {
+		return nil
+	}
+
+	delete(p.positions, songPath)
+
+	return p.save()
+}
+
+// Resumes the song from the position the next time it is played
+func (p *Positions) resumeOnce(songPath string, pos time.Duration) {
+	p.mu.Lock()
+	p.once[songPath] = pos
+	p.mu.Unlock()
+}
+
+// Gets the position to resume the song from
+func (p *Positions) get(songPath string) (time.Duration, bool) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pos, ok := p.once[songPath]; ok {
+		delete(p.once, songPath)
+		return pos, true
+	}
+
+	pos, ok := p.positions[songPath]
+	return pos, ok
+}
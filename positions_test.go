@@ -0,0 +1,47 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPositions(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "gomu", "positions")
+	p := newPositions(path)
+	p.threshold = 20 * time.Minute
+
+	const (
+		book  = "/music/audio book.mp3"
+		short = "/music/song.mp3"
+	)
+
+	assert.NoError(t, p.remember(book, 5*time.Minute, time.Hour))
+	// too short to be remembered
+	assert.NoError(t, p.remember(short, 2*time.Minute, 3*time.Minute))
+
+	loaded := newPositions(path)
+	assert.NoError(t, loaded.load())
+
+	pos, ok := loaded.get(book)
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Minute, pos)
+
+	_, ok = loaded.get(short)
+	assert.False(t, ok)
+
+	// played to the end
+	assert.NoError(t, p.remember(book, time.Hour, time.Hour))
+	_, ok = p.get(book)
+	assert.False(t, ok)
+
+	p.resumeOnce(short, time.Minute)
+	pos, ok = p.get(short)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, pos)
+	_, ok = p.get(short)
+	assert.False(t, ok)
+}
@@ -7,11 +7,9 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"fmt"
-	"io/ioutil"
-	"math/rand"
 	"os"
-	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,9 +23,26 @@ import (
 // Queue shows queued songs for playing
 type Queue struct {
 	*tview.List
+	// queue cache of older versions, see migrateQueue
 	savedQueuePath string
 	items          []*player.AudioFile
-	isLoop         bool
+	repeat         repeatMode
+	// playback stops once the current song finishes
+	stopAfterCurrent bool
+	// shuffle to be undone by unshuffle, nil if there is none
+	lastShuffle *lastShuffle
+	// songs marked for bulk operations
+	marked map[*player.AudioFile]bool
+	// visual selection spans from visualStart to the row under the cursor
+	visual      bool
+	visualStart int
+	// songs added by play next are inserted here so they play in the order
+	// they are added, it goes back to the front when the next song starts
+	nextIndex int
+	// edits of the queue to be undone and redone, see Queue.record
+	undoLog   []queueEdit
+	redoLog   []queueEdit
+	undoDepth int
 }
 
 // Highlight the next item in the queue
@@ -73,11 +88,16 @@ func (q *Queue) deleteItem(index int) (*player.AudioFile, error) {
 		}
 
 		q.items = nItems
+		// songs added by play next keep their place
+		if index < q.nextIndex {
+			q.nextIndex--
+		}
 		// here we move to next item if not at the end
 		if index < len(q.items) {
 			q.next()
 		}
 		q.updateTitle()
+		q.updateNextSong()
 
 	}
 
@@ -103,26 +123,21 @@ func (q *Queue) updateTitle() string {
 		count = "song"
 	}
 
-	var loop string
+	loop := q.repeat.label(gomu.anko.GetBool("General.use_emoji"))
 
-	isEmoji := gomu.anko.GetBool("General.use_emoji")
+	if q.stopAfterCurrent {
+		loop += " | stop after current"
+	}
 
-	if q.isLoop {
-		if isEmoji {
-			loop = gomu.anko.GetString("Emoji.loop")
-		} else {
-			loop = "Loop"
-		}
-	} else {
-		if isEmoji {
-			loop = gomu.anko.GetString("Emoji.noloop")
-		} else {
-			loop = "No loop"
-		}
+	var sleep string
+
+	gomu.sleepTimer.updateQueue(q.items)
+	if gomu.sleepTimer.isActive() {
+		sleep = " | sleep " + fmtDuration(gomu.sleepTimer.remaining())
 	}
 
-	title := fmt.Sprintf("─ Queue ───┤ %d %s | %s | %s ├",
-		len(q.items), count, fmtTime, loop)
+	title := fmt.Sprintf("─ Queue ───┤ %d %s | %s | %s%s ├",
+		len(q.items), count, fmtTime, loop, sleep)
 
 	q.SetTitle(title)
 
@@ -133,6 +148,9 @@ func (q *Queue) updateTitle() string {
 func (q *Queue) pushFront(audioFile *player.AudioFile) {
 
 	q.items = append([]*player.AudioFile{audioFile}, q.items...)
+	if q.nextIndex > 0 {
+		q.nextIndex++
+	}
 
 	songLength := audioFile.Len()
 
@@ -142,6 +160,7 @@ func (q *Queue) pushFront(audioFile *player.AudioFile) {
 
 	q.InsertItem(0, queueItemView, audioFile.Path(), 0, nil)
 	q.updateTitle()
+	q.updateNextSong()
 }
 
 // gets the first item and remove it from the queue
@@ -154,11 +173,23 @@ func (q *Queue) dequeue() (*player.AudioFile, error) {
 
 	first := q.items[0]
 	q.deleteItem(0)
+	q.forgetPlayed(first)
 	q.updateTitle()
 
 	return first, nil
 }
 
+// Removes the first occurrence of the audio file from the queue
+func (q *Queue) remove(audioFile *player.AudioFile) {
+	for i, v := range q.items {
+		if v == audioFile {
+			q.deleteItem(i)
+			q.forgetPlayed(audioFile)
+			return
+		}
+	}
+}
+
 // Add item to the list and returns the length of the queue
 func (q *Queue) enqueue(audioFile *player.AudioFile) (int, error) {
 
@@ -166,18 +197,22 @@ func (q *Queue) enqueue(audioFile *player.AudioFile) (int, error) {
 		return q.GetItemCount(), nil
 	}
 
-	q.items = append(q.items, audioFile)
-	songLength, err := getTagLength(audioFile.Path())
+	// the song is added once it can be read, so the songs stay in step with
+	// the list
+	length, err := songLength(audioFile)
 
 	if err != nil {
 		return 0, tracerr.Wrap(err)
 	}
 
+	q.items = append(q.items, audioFile)
+
 	queueItemView := fmt.Sprintf(
-		"[ %s ] %s", fmtDuration(songLength), getName(audioFile.Name()),
+		"[ %s ] %s", fmtDuration(length), getName(audioFile.Name()),
 	)
 	q.AddItem(queueItemView, audioFile.Path(), 0, nil)
 	q.updateTitle()
+	q.updateNextSong()
 
 	return q.GetItemCount(), nil
 }
@@ -199,54 +234,76 @@ func (q *Queue) getItems() []string {
 	return items
 }
 
-// Save the current queue
-func (q *Queue) saveQueue() error {
+// Clears current queue
+func (q *Queue) clearQueue() {
 
-	songPaths := q.getItems()
-	var content strings.Builder
+	q.items = []*player.AudioFile{}
+	q.nextIndex = 0
+	q.Clear()
+	q.updateTitle()
+	q.updateNextSong()
 
-	if gomu.player.HasInit() && gomu.player.GetCurrentSong() != nil {
-		currentSongPath := gomu.player.GetCurrentSong().Path()
-		currentSongInQueue := false
-		for _, songPath := range songPaths {
-			if getName(songPath) == getName(currentSongPath) {
-				currentSongInQueue = true
-			}
-		}
-		if !currentSongInQueue && len(q.items) != 0 {
-			hashed := sha1Hex(getName(currentSongPath))
-			content.WriteString(hashed + "\n")
-		}
+}
+
+// Loads the queue of the previous session, the current song of the session
+// is put at the front of the queue to be resumed
+func (q *Queue) loadQueue() error {
+
+	state, err := readSession()
+	if os.IsNotExist(tracerr.Unwrap(err)) {
+		return q.migrateQueue()
+	} else if err != nil {
+		return tracerr.Wrap(err)
 	}
 
-	for _, songPath := range songPaths {
-		// hashed song name is easier to search through
-		hashed := sha1Hex(getName(songPath))
-		content.WriteString(hashed + "\n")
+	// queue was saved to queue.cache before the session had it
+	if state.Version < sessionVersion {
+		return q.migrateQueue()
 	}
 
-	savedPath := expandTilde(q.savedQueuePath)
-	err := ioutil.WriteFile(savedPath, []byte(content.String()), 0644)
+	finder := newSongFinder(gomu.playlist.GetRoot())
 
-	if err != nil {
-		return tracerr.Wrap(err)
+	// songs which have been removed since the queue was saved
+	var missing int
+
+	if state.Current != nil {
+		audioFile := finder.find(*state.Current)
+		if audioFile != nil {
+			position := time.Duration(state.PositionMs) * time.Millisecond
+			if position > 0 {
+				gomu.positions.resumeOnce(positionKey(audioFile), position)
+			}
+			q.enqueue(audioFile)
+		} else {
+			missing++
+		}
 	}
 
-	return nil
+	for _, song := range state.Queue {
 
-}
+		audioFile := finder.find(song)
+		if audioFile == nil {
+			missing++
+			continue
+		}
 
-// Clears current queue
-func (q *Queue) clearQueue() {
+		q.enqueue(audioFile)
+	}
 
-	q.items = []*player.AudioFile{}
-	q.Clear()
-	q.updateTitle()
+	if state.Shuffle != nil {
+		q.loadShuffle(finder, *state.Shuffle)
+	}
+
+	if missing > 0 {
+		return tracerr.Errorf("%d songs of the saved queue could not be found", missing)
+	}
 
+	return nil
 }
 
-// Loads previously saved list
-func (q *Queue) loadQueue() error {
+// Loads the queue saved by older versions as hashed song names, it is saved
+// to the session from now on
+func (q *Queue) migrateQueue() error {
 
 	songs, err := q.getSavedQueue()
 
@@ -254,43 +311,49 @@ func (q *Queue) loadQueue() error {
 		return tracerr.Wrap(err)
 	}
 
+	// songs which have been removed or renamed since the queue was saved
+	var missing int
+
 	for _, v := range songs {
 
-		audioFile, err := gomu.playlist.findAudioFile(v)
+		// hashed name optionally followed by the position in seconds
+		fields := strings.Fields(v)
+		if len(fields) == 0 {
+			continue
+		}
+
+		audioFile, err := gomu.playlist.findAudioFile(fields[0])
 
 		if err != nil {
-			logError(err)
+			missing++
 			continue
 		}
 
+		if len(fields) > 1 {
+			seconds, err := strconv.Atoi(fields[1])
+			if err == nil && seconds > 0 {
+				gomu.positions.resumeOnce(positionKey(audioFile), time.Duration(seconds)*time.Second)
+			}
+		}
+
 		q.enqueue(audioFile)
 	}
 
+	if missing > 0 {
+		return tracerr.Errorf("%d songs of the saved queue could not be found", missing)
+	}
+
 	return nil
 }
 
-// Get saved queue, if not exist, create it
+// Gets the queue saved by older versions, empty if there is none
 func (q *Queue) getSavedQueue() ([]string, error) {
 
 	queuePath := expandTilde(q.savedQueuePath)
 
 	if _, err := os.Stat(queuePath); os.IsNotExist(err) {
-
-		dir, _ := path.Split(queuePath)
-		err := os.MkdirAll(dir, 0744)
-		if err != nil {
-			return nil, tracerr.Wrap(err)
-		}
-
-		_, err = os.Create(queuePath)
-		if err != nil {
-			return nil, tracerr.Wrap(err)
-		}
-
 		return []string{}, nil
-
 	}
-
 	f, err := os.Open(queuePath)
 	if err != nil {
 		return nil, tracerr.Wrap(err)
@@ -317,38 +380,60 @@ func (q *Queue) help() []string {
 		"k      up",
 		"l      play selected song",
 		"d      remove from queue",
+		"V      visual selection",
+		"M      mark song",
+		"esc    clear selection",
+		"J/K    move songs down/up",
+		"p      add songs to playlist",
 		"D      clear queue",
-		"z      toggle loop",
+		"u      undo queue edit",
+		"C-r    redo queue edit",
+		"z      cycle repeat mode off/all/one",
+		"x      stop after current song",
 		"s      shuffle",
+		"a      shuffle spreading out artists",
+		"A      shuffle albums",
+		"U      undo shuffle",
 		"/      find in queue",
 		"t      lyric delay increase 0.5 second",
 		"r      lyric delay decrease 0.5 second",
+		"w      save queue as m3u8 playlist",
+		"o      load playlist file into queue",
+		"W      save queue under a name",
+		"O      load saved queue",
+		"R      rename saved queue",
+		"X      delete saved queue",
 	}
 
 }
 
 // Shuffles the queue
 func (q *Queue) shuffle() {
+	q.shuffleBy(shuffleRandom, time.Now().UnixNano())
+}
 
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(q.items), func(i, j int) {
-		q.items[i], q.items[j] = q.items[j], q.items[i]
-	})
+// Rebuilds the list from the items after they have been reordered, the
+// length of the songs is already known so the tags are not read again. Songs
+// added by play next go to the front again as their place is lost.
+func (q *Queue) redraw() {
+
+	current := q.GetCurrentItem()
+	q.nextIndex = 0
 
 	q.Clear()
 
 	for _, v := range q.items {
-		audioLen, err := getTagLength(v.Path())
-		if err != nil {
-			logError(err)
-		}
-
-		queueText := fmt.Sprintf("[ %s ] %s", fmtDuration(audioLen), v.Name())
+		queueText := fmt.Sprintf("[ %s ] %s", fmtDuration(v.Len()), getName(v.Name()))
 		q.AddItem(queueText, v.Path(), 0, nil)
 	}
 
-	// q.updateTitle()
+	if current >= 0 && current < len(q.items) {
+		q.SetCurrentItem(current)
+	}
 
+	q.updateMarks()
+	q.updateTitle()
+	q.updateNextSong()
 }
 
 // Initiliaze new queue with default values
@@ -363,19 +448,36 @@ func newQueue() *Queue {
 	queue := &Queue{
 		List:           list,
 		savedQueuePath: cacheQueuePath,
+		undoDepth:      getUndoDepth(),
 	}
 
 	cmds := map[rune]string{
 		'j': "move_down",
 		'k': "move_up",
 		'd': "delete_item",
+		'V': "toggle_visual",
+		'M': "toggle_mark",
+		'J': "move_item_down",
+		'K': "move_item_up",
+		'p': "send_to_playlist",
 		'D': "clear_queue",
 		'l': "play_selected",
 		'z': "toggle_loop",
+		'x': "toggle_stop_after",
 		's': "shuffle_queue",
+		'a': "shuffle_artist",
+		'A': "shuffle_album",
+		'U': "unshuffle",
 		'/': "queue_search",
 		't': "lyric_delay_increase",
 		'r': "lyric_delay_decrease",
+		'w': "export_queue",
+		'o': "import_queue",
+		'W': "save_snapshot",
+		'O': "load_snapshot",
+		'R': "rename_snapshot",
+		'X': "delete_snapshot",
+		'u': "undo",
 	}
 
 	for key, cmdName := range cmds {
@@ -383,6 +485,16 @@ func newQueue() *Queue {
 		gomu.anko.Execute(src)
 	}
 
+	gomu.anko.Execute(`Keybinds.def_q("esc", clear_selection)`)
+	gomu.anko.Execute(`Keybinds.def_q("ctrl_r", redo)`)
+
+	// visual selection follows the cursor
+	queue.SetChangedFunc(func(int, string, string, rune) {
+		if queue.visual {
+			queue.updateMarks()
+		}
+	})
+
 	queue.SetInputCapture(func(e *tcell.EventKey) *tcell.EventKey {
 
 		if gomu.anko.KeybindExists("queue", e) {
@@ -456,36 +568,36 @@ func (q *Queue) playQueue() error {
 	return nil
 }
 
+// Inserts the song at the index, index equal to the number of songs appends
+// the song
 func (q *Queue) insertItem(index int, audioFile *player.AudioFile) error {
 
-	if index > len(q.items)-1 {
+	if index > len(q.items) {
 		return tracerr.New("Index out of range")
 	}
 
 	if index != -1 {
-		songLength, err := getTagLength(audioFile.Path())
+		length, err := songLength(audioFile)
 		if err != nil {
 			return tracerr.Wrap(err)
 		}
 		queueItemView := fmt.Sprintf(
-			"[ %s ] %s", fmtDuration(songLength), getName(audioFile.Name()),
+			"[ %s ] %s", fmtDuration(length), getName(audioFile.Name()),
 		)
 
 		q.InsertItem(index, queueItemView, audioFile.Path(), 0, nil)
 
-		var nItems []*player.AudioFile
-
-		for i, v := range q.items {
-
-			if i == index {
-				nItems = append(nItems, audioFile)
-			}
-
-			nItems = append(nItems, v)
-		}
+		nItems := make([]*player.AudioFile, 0, len(q.items)+1)
+		nItems = append(nItems, q.items[:index]...)
+		nItems = append(nItems, audioFile)
+		nItems = append(nItems, q.items[index:]...)
 
 		q.items = nItems
+		if index < q.nextIndex {
+			q.nextIndex++
+		}
 		q.updateTitle()
+		q.updateNextSong()
 
 	}
 
@@ -533,16 +645,33 @@ func (q *Queue) updateCurrentSongName(oldAudio *player.AudioFile, newAudio *play
 		return nil
 	}
 
-	// we insert it in the first of queue, then play it
-	gomu.queue.pushFront(newAudio)
-	tmpLoop := q.isLoop
-	q.isLoop = false
-	gomu.player.Skip()
-	gomu.player.Seek(position)
+	return q.replaceCurrentSong(newAudio, position, paused)
+}
+
+// Plays the song in place of the current song from the same position
+func (q *Queue) replaceCurrentSong(
+	audioFile *player.AudioFile, position int, paused bool,
+) error {
+
+	// the song is replaced by itself, it is not played again by prev
+	current, _ := gomu.player.GetCurrentSong().(*player.AudioFile)
+	gomu.history.skipNext(current)
+
+	err := gomu.player.Run(audioFile)
+	if err != nil {
+		gomu.history.skipNext(nil)
+		return tracerr.Wrap(err)
+	}
+
+	err = gomu.player.Seek(position)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
 	if paused {
-		gomu.player.TogglePause()
+		gomu.player.Pause()
 	}
-	q.isLoop = tmpLoop
+
 	q.updateTitle()
 
 	return nil
@@ -569,19 +698,8 @@ func (q *Queue) updateCurrentSongPath(oldAudio *player.AudioFile, newAudio *play
 	if err != nil {
 		return tracerr.Wrap(err)
 	}
-	gomu.queue.pushFront(currentSongAudioFile)
-	tmpLoop := q.isLoop
-	q.isLoop = false
-	gomu.player.Skip()
-	gomu.player.Seek(position)
-	if paused {
-		gomu.player.TogglePause()
-	}
-	q.isLoop = tmpLoop
-
-	q.updateTitle()
-	return nil
 
+	return q.replaceCurrentSong(currentSongAudioFile, position, paused)
 }
 
 // update current playing song simply delete it
@@ -608,13 +726,18 @@ func (q *Queue) updateCurrentSongDelete(oldAudio *player.AudioFile) {
 		return
 	}
 
-	tmpLoop := q.isLoop
-	q.isLoop = false
-	gomu.player.Skip()
-	if paused {
-		gomu.player.TogglePause()
+	// the deleted song is not finished so it is not added back in loop mode
+	gomu.player.Stop()
+
+	if len(q.items) > 0 {
+		err := q.playQueue()
+		if err != nil {
+			logError(err)
+		}
+		if paused {
+			gomu.player.Pause()
+		}
 	}
-	q.isLoop = tmpLoop
-	q.updateTitle()
 
+	q.updateTitle()
 }
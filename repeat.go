@@ -0,0 +1,137 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"strings"
+
+	"github.com/ztrue/tracerr"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// repeatMode decides what happens to the song after it finishes
+type repeatMode int
+
+const (
+	// song is removed from the queue once played
+	repeatOff repeatMode = iota
+	// song is added back to the end of the queue
+	repeatAll
+	// song is played again
+	repeatOne
+)
+
+var repeatModes = []string{"off", "all", "one"}
+
+func (r repeatMode) String() string {
+	if r < 0 || int(r) >= len(repeatModes) {
+		return repeatModes[repeatOff]
+	}
+	return repeatModes[r]
+}
+
+// Gets the mode which comes after this one when cycling the modes
+func (r repeatMode) next() repeatMode {
+	return (r + 1) % repeatMode(len(repeatModes))
+}
+
+// Parses the name of repeat mode, eg. "off", "all" or "one"
+func parseRepeatMode(s string) (repeatMode, error) {
+
+	for i, name := range repeatModes {
+		if strings.EqualFold(strings.TrimSpace(s), name) {
+			return repeatMode(i), nil
+		}
+	}
+
+	return repeatOff, tracerr.Errorf("invalid repeat mode %q, expected one of %s",
+		s, strings.Join(repeatModes, ", "))
+}
+
+// Gets the text shown in the queue title for the repeat mode
+func (r repeatMode) label(isEmoji bool) string {
+
+	if isEmoji {
+		switch r {
+		case repeatAll:
+			return gomu.anko.GetString("Emoji.loop")
+		case repeatOne:
+			return gomu.anko.GetString("Emoji.repeat_one")
+		default:
+			return gomu.anko.GetString("Emoji.noloop")
+		}
+	}
+
+	switch r {
+	case repeatAll:
+		return "Loop"
+	case repeatOne:
+		return "Repeat one"
+	default:
+		return "No loop"
+	}
+}
+
+// Sets the repeat mode and saves it to the session
+func (q *Queue) setRepeat(mode repeatMode) {
+	q.repeat = mode
+	q.updateTitle()
+	q.updateNextSong()
+	err := saveSession()
+	if err != nil {
+		logError(err)
+	}
+}
+
+// Toggles stopping the playback after the current song finishes
+func (q *Queue) toggleStopAfterCurrent() {
+	q.stopAfterCurrent = !q.stopAfterCurrent
+	q.updateTitle()
+	q.updateNextSong()
+	err := saveSession()
+	if err != nil {
+		logError(err)
+	}
+}
+
+// Gets the song to be played after the current one, nil if the playback
+// stops after it
+func (q *Queue) nextSong() *player.AudioFile {
+
+	if q.stopAfterCurrent {
+		return nil
+	}
+
+	current, _ := gomu.player.GetCurrentSong().(*player.AudioFile)
+
+	if q.repeat == repeatOne && current != nil {
+		return current
+	}
+
+	if len(q.items) > 0 {
+		return q.items[0]
+	}
+
+	if q.repeat == repeatAll && current != nil {
+		return current
+	}
+
+	return nil
+}
+
+// Tells the player the song to be played after the current one. It is called
+// whenever the queue changes, so the song opened ahead of time is up to date.
+func (q *Queue) updateNextSong() {
+
+	if gomu.player == nil {
+		return
+	}
+
+	// nil song must not be passed as non-nil interface
+	if next := q.nextSong(); next != nil {
+		gomu.player.SetNext(next)
+	} else {
+		gomu.player.SetNext(nil)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+func TestRepeatMode(t *testing.T) {
+
+	mode := repeatOff
+	for _, expected := range []string{"all", "one", "off"} {
+		mode = mode.next()
+		assert.Equal(t, expected, mode.String())
+	}
+
+	for _, name := range []string{"off", "all", "one"} {
+		mode, err := parseRepeatMode(name)
+		assert.NoError(t, err)
+		assert.Equal(t, name, mode.String())
+	}
+
+	mode, err := parseRepeatMode(" ONE ")
+	assert.NoError(t, err)
+	assert.Equal(t, repeatOne, mode)
+
+	_, err = parseRepeatMode("twice")
+	assert.Error(t, err)
+}
+
+func TestQueueNextSong(t *testing.T) {
+
+	gomu = newGomu()
+	gomu.player = player.New(0)
+	gomu.queue = &Queue{List: tview.NewList()}
+
+	song := new(player.AudioFile)
+	gomu.queue.items = []*player.AudioFile{song}
+
+	assert.Equal(t, song, gomu.queue.nextSong())
+
+	gomu.queue.stopAfterCurrent = true
+	assert.Nil(t, gomu.queue.nextSong())
+
+	// nothing is playing to be repeated
+	gomu.queue.stopAfterCurrent = false
+	gomu.queue.repeat = repeatOne
+	assert.Equal(t, song, gomu.queue.nextSong())
+
+	gomu.queue.items = nil
+	gomu.queue.repeat = repeatAll
+	assert.Nil(t, gomu.queue.nextSong())
+}
@@ -0,0 +1,265 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ztrue/tracerr"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// Gets the color tag prepended to the text of marked rows
+func markTag() string {
+	return fmt.Sprintf("[%s]", gomu.colors.queueMark)
+}
+
+// Checks if the row is in the visual selection or marked
+func (q *Queue) isSelected(index int) bool {
+
+	if index < 0 || index >= len(q.items) {
+		return false
+	}
+
+	if q.visual {
+		start, end := q.visualStart, q.GetCurrentItem()
+		if start > end {
+			start, end = end, start
+		}
+		if index >= start && index <= end {
+			return true
+		}
+	}
+
+	return q.marked[q.items[index]]
+}
+
+// Gets the rows in the visual selection or marked in ascending order
+func (q *Queue) selection() []int {
+
+	var rows []int
+	for i := range q.items {
+		if q.isSelected(i) {
+			rows = append(rows, i)
+		}
+	}
+
+	return rows
+}
+
+// Gets the selected rows, the row under the cursor if nothing is selected
+func (q *Queue) selectionOrCurrent() []int {
+
+	if rows := q.selection(); len(rows) > 0 {
+		return rows
+	}
+
+	current := q.GetCurrentItem()
+	if current < 0 || current >= len(q.items) {
+		return nil
+	}
+
+	return []int{current}
+}
+
+// Starts visual selection from the row under the cursor, the selection is
+// marked when the visual mode ends
+func (q *Queue) toggleVisual() {
+
+	if q.visual {
+		for _, i := range q.selection() {
+			q.markItem(i, true)
+		}
+		q.visual = false
+	} else if q.GetCurrentItem() >= 0 {
+		q.visual = true
+		q.visualStart = q.GetCurrentItem()
+	}
+
+	q.updateMarks()
+}
+
+// Toggles the mark of the row under the cursor and moves to the next row
+func (q *Queue) toggleMark() {
+
+	current := q.GetCurrentItem()
+	if current < 0 || current >= len(q.items) {
+		return
+	}
+
+	q.markItem(current, !q.marked[q.items[current]])
+	q.updateMarks()
+
+	if current < len(q.items)-1 {
+		q.SetCurrentItem(current + 1)
+	}
+}
+
+func (q *Queue) markItem(index int, mark bool) {
+
+	if q.marked == nil {
+		q.marked = make(map[*player.AudioFile]bool)
+	}
+
+	if mark {
+		q.marked[q.items[index]] = true
+	} else {
+		delete(q.marked, q.items[index])
+	}
+}
+
+// Unmarks all rows and ends the visual selection
+func (q *Queue) clearSelection() {
+	q.marked = nil
+	q.visual = false
+	q.updateMarks()
+}
+
+// Colors the selected rows
+func (q *Queue) updateMarks() {
+
+	tag := markTag()
+
+	for i := 0; i < q.GetItemCount() && i < len(q.items); i++ {
+		main, secondary := q.GetItemText(i)
+		text := strings.TrimPrefix(main, tag)
+		if q.isSelected(i) {
+			text = tag + text
+		}
+		if text != main {
+			q.SetItemText(i, text, secondary)
+		}
+	}
+}
+
+// Swaps the rows and their songs
+func (q *Queue) swapItems(i, j int) {
+
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+
+	mainI, secondaryI := q.GetItemText(i)
+	mainJ, secondaryJ := q.GetItemText(j)
+	q.SetItemText(i, mainJ, secondaryJ)
+	q.SetItemText(j, mainI, secondaryI)
+	q.updateNextSong()
+}
+
+// Moves the selected rows, or the row under the cursor, up or down by one row.
+// The rows keep their order and the block stops at the edge of the queue.
+func (q *Queue) moveSelection(down bool) {
+
+	rows := q.selectionOrCurrent()
+	if len(rows) == 0 {
+		return
+	}
+
+	current := q.GetCurrentItem()
+	cursorMoves := false
+	for _, i := range rows {
+		cursorMoves = cursorMoves || i == current
+	}
+
+	step := -1
+	if down {
+		step = 1
+		// the last row moves first so it makes room for the others
+		sort.Sort(sort.Reverse(sort.IntSlice(rows)))
+	}
+
+	if edge := rows[0] + step; edge < 0 || edge >= len(q.items) {
+		return
+	}
+
+	for _, i := range rows {
+		q.swapItems(i, i+step)
+	}
+
+	// the visual selection and the cursor move along with the rows
+	if q.visual {
+		q.visualStart += step
+	}
+	if cursorMoves {
+		q.SetCurrentItem(current + step)
+	}
+	// songs added by play next are no longer in the order they were added
+	q.nextIndex = 0
+	q.updateMarks()
+}
+
+// Removes the selected rows, or the row under the cursor, from the queue
+func (q *Queue) deleteSelection() {
+
+	rows := q.selectionOrCurrent()
+
+	for i := len(rows) - 1; i >= 0; i-- {
+		_, err := q.deleteItem(rows[i])
+		if err != nil {
+			logError(err)
+		}
+	}
+
+	q.clearSelection()
+}
+
+// Gets the songs of the selected rows, or of the row under the cursor
+func (q *Queue) selectedSongs() []*player.AudioFile {
+
+	var songs []*player.AudioFile
+	for _, i := range q.selectionOrCurrent() {
+		songs = append(songs, q.items[i])
+	}
+
+	return songs
+}
+
+// Adds the songs to the end of m3u8 playlist, the playlist is created if it
+// does not exist
+func appendPlaylistFile(playlistPath string, songs []*player.AudioFile) error {
+
+	switch strings.ToLower(filepath.Ext(playlistPath)) {
+	case ".m3u", ".m3u8":
+	default:
+		return tracerr.Errorf("unable to add songs to %s, only m3u playlist is supported",
+			filepath.Base(playlistPath))
+	}
+
+	var existing []*player.AudioFile
+
+	if _, err := os.Stat(playlistPath); err == nil {
+		var unresolved []string
+		existing, unresolved, err = readPlaylistFile(playlistPath)
+		if err != nil {
+			return tracerr.Wrap(err)
+		}
+		// rewriting the playlist would lose these entries
+		if len(unresolved) > 0 {
+			return tracerr.Errorf("%s has %d unresolved entries",
+				filepath.Base(playlistPath), len(unresolved))
+		}
+	}
+
+	var content strings.Builder
+
+	err := writeM3U8(&content, playlistPath, append(existing, songs...))
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(playlistPath), 0744)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	err = ioutil.WriteFile(playlistPath, []byte(content.String()), 0644)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
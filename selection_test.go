@@ -0,0 +1,117 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// prepares the queue with the songs of the rap playlist
+func prepareSelectionTest(t *testing.T) []*player.AudioFile {
+
+	prepareEqualizerTest(t)
+	gomu.colors = newColor()
+	gomu = prepareTest()
+
+	rapDir := gomu.playlist.GetRoot().GetChildren()[1]
+	gomu.playlist.addAllToQueue(rapDir)
+
+	songs := make([]*player.AudioFile, len(gomu.queue.items))
+	copy(songs, gomu.queue.items)
+
+	return songs
+}
+
+func TestQueueSelection(t *testing.T) {
+
+	songs := prepareSelectionTest(t)
+	q := gomu.queue
+
+	q.SetCurrentItem(0)
+	q.toggleVisual()
+	q.SetCurrentItem(1)
+	assert.Equal(t, []int{0, 1}, q.selection())
+
+	// visual selection is marked once it ends
+	q.toggleVisual()
+	q.SetCurrentItem(2)
+	assert.Equal(t, []int{0, 1}, q.selection())
+
+	main, _ := q.GetItemText(0)
+	assert.True(t, strings.HasPrefix(main, markTag()))
+	main, _ = q.GetItemText(2)
+	assert.False(t, strings.HasPrefix(main, markTag()))
+
+	q.SetCurrentItem(1)
+	q.toggleMark()
+	assert.Equal(t, []int{0}, q.selection())
+
+	q.clearSelection()
+	assert.Empty(t, q.selection())
+	assert.Equal(t, []int{2}, q.selectionOrCurrent())
+	main, _ = q.GetItemText(0)
+	assert.False(t, strings.HasPrefix(main, markTag()))
+
+	assert.Equal(t, songs, q.items)
+}
+
+func TestMoveSelection(t *testing.T) {
+
+	songs := prepareSelectionTest(t)
+	q := gomu.queue
+
+	q.SetCurrentItem(0)
+	q.toggleVisual()
+	q.SetCurrentItem(1)
+
+	q.moveSelection(true)
+	assert.Equal(t, []*player.AudioFile{songs[2], songs[0], songs[1]}, q.items)
+	assert.Equal(t, []int{1, 2}, q.selection())
+
+	// the block stops at the edge of the queue
+	q.moveSelection(true)
+	assert.Equal(t, []*player.AudioFile{songs[2], songs[0], songs[1]}, q.items)
+
+	q.moveSelection(false)
+	assert.Equal(t, songs, q.items)
+
+	_, secondary := q.GetItemText(0)
+	assert.Equal(t, songs[0].Path(), secondary)
+}
+
+func TestDeleteSelection(t *testing.T) {
+
+	songs := prepareSelectionTest(t)
+	q := gomu.queue
+
+	q.SetCurrentItem(0)
+	q.toggleMark()
+	q.SetCurrentItem(2)
+	q.toggleMark()
+
+	q.deleteSelection()
+	assert.Equal(t, []*player.AudioFile{songs[1]}, q.items)
+	assert.Equal(t, 1, q.GetItemCount())
+	assert.Empty(t, q.selection())
+}
+
+func TestAppendPlaylistFile(t *testing.T) {
+
+	songs := prepareSelectionTest(t)
+	playlistPath := filepath.Join(t.TempDir(), "mix.m3u8")
+
+	assert.NoError(t, appendPlaylistFile(playlistPath, songs[:1]))
+	assert.NoError(t, appendPlaylistFile(playlistPath, songs[1:]))
+
+	got, unresolved, err := readPlaylistFile(playlistPath)
+	assert.NoError(t, err)
+	assert.Empty(t, unresolved)
+	assert.Len(t, got, len(songs))
+	assert.Equal(t, songs[2].Path(), got[2].Path())
+
+	assert.Error(t, appendPlaylistFile(filepath.Join(t.TempDir(), "mix.pls"), songs))
+}
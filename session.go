@@ -0,0 +1,341 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/ztrue/tracerr"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// sessionVersion is the version of the session file. Version 0 has no queue,
+// the queue was saved to queue.cache as hashed song names.
+const sessionVersion = 1
+
+// songs whose length differ by less than this are taken as the same song
+const lengthTolerance = time.Second
+
+// sessionState is saved to the cache so the queue and the playback settings
+// are restored on the next start
+type sessionState struct {
+	Version          int    `json:"version"`
+	Repeat           string `json:"repeat"`
+	StopAfterCurrent bool   `json:"stop_after_current"`
+	// song being played when gomu was closed, it is played first
+	Current    *sessionSong  `json:"current,omitempty"`
+	PositionMs int64         `json:"position_ms,omitempty"`
+	Queue      []sessionSong `json:"queue"`
+	// last shuffle of the queue, see Queue.unshuffle
+	Shuffle *sessionShuffle `json:"shuffle,omitempty"`
+}
+
+// sessionShuffle is the shuffle which can be undone after restart
+type sessionShuffle struct {
+	Mode   string        `json:"mode"`
+	Seed   int64         `json:"seed"`
+	Before []sessionSong `json:"before"`
+}
+
+// sessionSong refers to the song by its path, the tags are used to find the
+// song when it has been moved
+type sessionSong struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+	// bounds of track of cue sheet
+	StartMs  int64  `json:"start_ms,omitempty"`
+	EndMs    int64  `json:"end_ms,omitempty"`
+	Artist   string `json:"artist,omitempty"`
+	Title    string `json:"title,omitempty"`
+	LengthMs int64  `json:"length_ms,omitempty"`
+}
+
+// Gets session cache path
+func sessionPath() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		logError(err)
+	}
+	return filepath.Join(cacheDir, "gomu", "session.json")
+}
+
+// Gets the song to be saved in the session
+func newSessionSong(audioFile *player.AudioFile) sessionSong {
+
+	song := sessionSong{
+		Path:     audioFile.Path(),
+		Name:     audioFile.Name(),
+		LengthMs: audioFile.Len().Milliseconds(),
+	}
+
+	if start, end, ok := audioFile.Bounds(); ok {
+		song.StartMs = start.Milliseconds()
+		song.EndMs = end.Milliseconds()
+	}
+
+	if player.IsStream(song.Path) {
+		return song
+	}
+
+	tags, err := getTags(song.Path)
+	if err != nil {
+		logError(err)
+	}
+	song.Artist = tags.artist
+	song.Title = tags.title
+
+	// tracks of cue sheet share the tags of the file
+	if isCueTrack(audioFile) {
+		song.Artist, song.Title = "", ""
+	}
+
+	return song
+}
+
+// Gets the current state of the queue
+func currentSession() sessionState {
+
+	state := sessionState{
+		Version:          sessionVersion,
+		Repeat:           gomu.queue.repeat.String(),
+		StopAfterCurrent: gomu.queue.stopAfterCurrent,
+		Queue:            []sessionSong{},
+	}
+
+	current, ok := gomu.player.GetCurrentSong().(*player.AudioFile)
+	if ok && current != nil && gomu.player.State() != player.Stopped &&
+		!player.IsStream(current.Path()) {
+		song := newSessionSong(current)
+		state.Current = &song
+		state.PositionMs = gomu.player.GetPosition().Milliseconds()
+	}
+
+	for _, item := range gomu.queue.items {
+		state.Queue = append(state.Queue, newSessionSong(item))
+	}
+
+	if last := gomu.queue.lastShuffle; last != nil {
+		state.Shuffle = &sessionShuffle{
+			Mode: last.mode.String(),
+			Seed: last.seed,
+		}
+		for _, item := range last.before {
+			state.Shuffle.Before = append(state.Shuffle.Before, newSessionSong(item))
+		}
+	}
+
+	return state
+}
+
+// Reads the session saved by the previous run
+func readSession() (sessionState, error) {
+
+	data, err := os.ReadFile(sessionPath())
+	if err != nil {
+		return sessionState{}, tracerr.Wrap(err)
+	}
+
+	var state sessionState
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return sessionState{}, tracerr.Wrap(err)
+	}
+
+	return state, nil
+}
+
+// Saves the state of the queue to the session cache. The queue of the previous
+// session is kept when gomu is opened with empty queue, the queue saved to
+// queue.cache by older versions is left to be migrated on the next start.
+func saveSession() error {
+
+	state := currentSession()
+
+	if gomu.args.empty != nil && *gomu.args.empty {
+		prev, err := readSession()
+		if err == nil && prev.Version == sessionVersion {
+			state.Current = prev.Current
+			state.PositionMs = prev.PositionMs
+			state.Queue = prev.Queue
+			state.Shuffle = prev.Shuffle
+		} else {
+			state = sessionState{
+				Repeat:           state.Repeat,
+				StopAfterCurrent: state.StopAfterCurrent,
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	cachePath := sessionPath()
+
+	err = os.MkdirAll(filepath.Dir(cachePath), 0755)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	err = os.WriteFile(cachePath, data, 0644)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// Restores the playback settings from the session cache, the config file is
+// used if there is no session yet. The queue is restored by Queue.loadQueue.
+func loadSession() error {
+
+	state, err := readSession()
+	if os.IsNotExist(tracerr.Unwrap(err)) {
+		return applyRepeatConfig()
+	} else if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	mode, err := parseRepeatMode(state.Repeat)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	gomu.queue.repeat = mode
+	gomu.queue.stopAfterCurrent = state.StopAfterCurrent
+
+	return nil
+}
+
+// Sets the repeat mode from config file, queue_loop is the older option which
+// repeats all songs
+func applyRepeatConfig() error {
+
+	mode, err := parseRepeatMode(gomu.anko.GetString("General.repeat_mode"))
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	if mode == repeatOff && gomu.anko.GetBool("General.queue_loop") {
+		mode = repeatAll
+	}
+
+	gomu.queue.repeat = mode
+
+	return nil
+}
+
+// songFinder finds the songs of the session in the playlist
+type songFinder struct {
+	root *tview.TreeNode
+	// tags of the songs in the playlist, read only when a song has moved
+	tags map[*player.AudioFile]songTags
+}
+
+func newSongFinder(root *tview.TreeNode) *songFinder {
+	return &songFinder{root: root}
+}
+
+// Walks through the audio files of the playlist until f returns true
+func (s *songFinder) walk(f func(*player.AudioFile) bool) *player.AudioFile {
+
+	var found *player.AudioFile
+
+	s.root.Walk(func(node, _ *tview.TreeNode) bool {
+		if found != nil {
+			return false
+		}
+		audioFile, ok := node.GetReference().(*player.AudioFile)
+		if ok && audioFile.IsAudioFile() && f(audioFile) {
+			found = audioFile
+		}
+		return found == nil
+	})
+
+	return found
+}
+
+// Finds the song by its path, then by its tags and lastly by its name.
+// Returns nil if the song is no longer in the playlist.
+func (s *songFinder) find(song sessionSong) *player.AudioFile {
+
+	start := time.Duration(song.StartMs) * time.Millisecond
+
+	found := s.walk(func(audioFile *player.AudioFile) bool {
+		trackStart, _, _ := audioFile.Bounds()
+		return audioFile.Path() == song.Path && trackStart == start
+	})
+	if found != nil {
+		return found
+	}
+
+	// streams and tracks of cue sheet are only found by their path
+	if player.IsStream(song.Path) || song.StartMs > 0 || song.EndMs > 0 {
+		return nil
+	}
+
+	if song.Title != "" {
+		found = s.walk(func(audioFile *player.AudioFile) bool {
+			tags := s.tagsOf(audioFile)
+			return strings.EqualFold(tags.title, song.Title) &&
+				strings.EqualFold(tags.artist, song.Artist) &&
+				sameLength(audioFile, song)
+		})
+		if found != nil {
+			return found
+		}
+	}
+
+	return s.walk(func(audioFile *player.AudioFile) bool {
+		return getName(audioFile.Name()) == getName(song.Name) &&
+			sameLength(audioFile, song)
+	})
+}
+
+// Gets the tags of the audio file, the tags are read once
+func (s *songFinder) tagsOf(audioFile *player.AudioFile) songTags {
+
+	if s.tags == nil {
+		s.tags = make(map[*player.AudioFile]songTags)
+	}
+
+	tags, ok := s.tags[audioFile]
+	if ok {
+		return tags
+	}
+
+	if !player.IsStream(audioFile.Path()) && !isCueTrack(audioFile) {
+		var err error
+		tags, err = getTags(audioFile.Path())
+		if err != nil {
+			logError(err)
+		}
+	}
+
+	s.tags[audioFile] = tags
+
+	return tags
+}
+
+// Checks if the audio file has the length of the song, unknown length matches
+// any length
+func sameLength(audioFile *player.AudioFile, song sessionSong) bool {
+
+	if audioFile.Len() == 0 || song.LengthMs == 0 {
+		return true
+	}
+
+	diff := audioFile.Len() - time.Duration(song.LengthMs)*time.Millisecond
+	if diff < 0 {
+		diff = -diff
+	}
+
+	return diff < lengthTolerance
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+func TestLoadSession(t *testing.T) {
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	prepareEqualizerTest(t)
+	gomu.queue = &Queue{List: tview.NewList()}
+
+	// nothing saved yet, uses config file
+	err := loadSession()
+	assert.NoError(t, err)
+	assert.Equal(t, repeatOff, gomu.queue.repeat)
+
+	gomu.queue.repeat = repeatOne
+	gomu.queue.stopAfterCurrent = true
+	assert.NoError(t, saveSession())
+
+	gomu.queue = &Queue{List: tview.NewList()}
+	assert.NoError(t, loadSession())
+	assert.Equal(t, repeatOne, gomu.queue.repeat)
+	assert.True(t, gomu.queue.stopAfterCurrent)
+}
+
+// returns the paths of the songs in the queue
+func queuePaths() []string {
+	var paths []string
+	for _, item := range gomu.queue.items {
+		paths = append(paths, item.Path())
+	}
+	return paths
+}
+
+func TestSessionQueue(t *testing.T) {
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	prepareEqualizerTest(t)
+	gomu.colors = newColor()
+	gomu = prepareTest()
+
+	songs := gomu.playlist.GetRoot().GetChildren()[1].GetChildren()
+	for _, node := range songs[:2] {
+		_, err := gomu.queue.enqueue(node.GetReference().(*player.AudioFile))
+		assert.NoError(t, err)
+	}
+	expected := queuePaths()
+
+	assert.NoError(t, saveSession())
+
+	gomu.queue = newQueue()
+	assert.NoError(t, gomu.queue.loadQueue())
+	assert.Equal(t, expected, queuePaths())
+
+	// moved song is found by its name
+	state, err := readSession()
+	assert.NoError(t, err)
+	assert.Equal(t, sessionVersion, state.Version)
+	state.Queue[0].Path = "/moved/" + filepath.Base(state.Queue[0].Path)
+	state.Queue = append(state.Queue, sessionSong{Path: "/removed.mp3", Name: "removed"})
+
+	data, err := json.Marshal(state)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(sessionPath(), data, 0644))
+
+	gomu.queue = newQueue()
+	assert.Error(t, gomu.queue.loadQueue())
+	assert.Equal(t, expected, queuePaths())
+}
+
+func TestMigrateQueue(t *testing.T) {
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	prepareEqualizerTest(t)
+	gomu.colors = newColor()
+	gomu = prepareTest()
+
+	song := gomu.playlist.GetRoot().GetChildren()[1].GetChildren()[0].
+		GetReference().(*player.AudioFile)
+
+	cachePath := gomu.queue.savedQueuePath
+	assert.NoError(t, os.MkdirAll(filepath.Dir(cachePath), 0755))
+	content := sha1Hex(getName(song.Name())) + " 30\n"
+	assert.NoError(t, os.WriteFile(cachePath, []byte(content), 0644))
+
+	// session without queue is from the older version
+	assert.NoError(t, os.WriteFile(sessionPath(), []byte(`{"repeat":"all"}`), 0644))
+
+	assert.NoError(t, gomu.queue.loadQueue())
+	assert.Equal(t, []string{song.Path()}, queuePaths())
+}
+
+func TestSaveSessionEmptyMigrate(t *testing.T) {
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	prepareEqualizerTest(t)
+	gomu.colors = newColor()
+	gomu = prepareTest()
+
+	song := gomu.playlist.GetRoot().GetChildren()[1].GetChildren()[0].
+		GetReference().(*player.AudioFile)
+
+	cachePath := gomu.queue.savedQueuePath
+	assert.NoError(t, os.MkdirAll(filepath.Dir(cachePath), 0755))
+	content := sha1Hex(getName(song.Name())) + "\n"
+	assert.NoError(t, os.WriteFile(cachePath, []byte(content), 0644))
+
+	// opened with empty queue before the queue has been migrated
+	empty := true
+	gomu.args.empty = &empty
+	gomu.queue.repeat = repeatAll
+	assert.NoError(t, saveSession())
+	gomu.args.empty = nil
+
+	gomu.queue = newQueue()
+	assert.NoError(t, loadSession())
+	assert.Equal(t, repeatAll, gomu.queue.repeat)
+	assert.NoError(t, gomu.queue.loadQueue())
+	assert.Equal(t, []string{song.Path()}, queuePaths())
+}
@@ -0,0 +1,291 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ztrue/tracerr"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// shuffleMode decides how the songs of the queue are mixed
+type shuffleMode int
+
+const (
+	// every order is equally likely
+	shuffleRandom shuffleMode = iota
+	// songs by the same artist are spread out over the queue
+	shuffleArtist
+	// albums are shuffled, songs are in track order inside the album
+	shuffleAlbum
+)
+
+var shuffleModes = []string{"random", "artist", "album"}
+
+func (s shuffleMode) String() string {
+	if s < 0 || int(s) >= len(shuffleModes) {
+		return shuffleModes[shuffleRandom]
+	}
+	return shuffleModes[s]
+}
+
+// Parses the name of shuffle mode, eg. "random", "artist" or "album"
+func parseShuffleMode(s string) (shuffleMode, error) {
+
+	for i, name := range shuffleModes {
+		if strings.EqualFold(strings.TrimSpace(s), name) {
+			return shuffleMode(i), nil
+		}
+	}
+
+	return shuffleRandom, tracerr.Errorf("invalid shuffle mode %q, expected one of %s",
+		s, strings.Join(shuffleModes, ", "))
+}
+
+// lastShuffle is the shuffle which can be undone, the queue is shuffled again
+// from the same order with the seed to get the same result
+type lastShuffle struct {
+	mode   shuffleMode
+	seed   int64
+	before []*player.AudioFile
+}
+
+// Gets the order of the songs after shuffling. The order only depends on the
+// seed and the songs, so the shuffle can be reproduced.
+func shuffleOrder(
+	songs []*player.AudioFile, tags []songTags, mode shuffleMode, seed int64,
+) []int {
+
+	r := rand.New(rand.NewSource(seed))
+
+	switch mode {
+	case shuffleArtist:
+		return spreadOrder(r, groupSongs(songs, tags, artistKey))
+	case shuffleAlbum:
+		groups := groupSongs(songs, tags, albumKey)
+		for _, group := range groups {
+			sortTracks(group, songs, tags)
+		}
+		r.Shuffle(len(groups), func(i, j int) {
+			groups[i], groups[j] = groups[j], groups[i]
+		})
+		var order []int
+		for _, group := range groups {
+			order = append(order, group...)
+		}
+		return order
+	}
+
+	return r.Perm(len(songs))
+}
+
+// Gets the artist the song is grouped by, songs of unknown artist are not
+// grouped together
+func artistKey(song *player.AudioFile, tags songTags) string {
+	if tags.artist == "" {
+		return "\x00" + song.Path() + song.Name()
+	}
+	return strings.ToLower(tags.artist)
+}
+
+// Gets the album the song is grouped by, the directory is taken as the album
+// when the song has no album tag. Albums of the same name in other directories
+// are different albums.
+func albumKey(song *player.AudioFile, tags songTags) string {
+	return strings.ToLower(tags.album) + "\x00" + filepath.Dir(song.Path())
+}
+
+// Sorts the indexes of the songs of an album by their track number, then by
+// their path. Songs without track number go last.
+func sortTracks(group []int, songs []*player.AudioFile, tags []songTags) {
+	sort.SliceStable(group, func(i, j int) bool {
+		a, b := group[i], group[j]
+		trackA, trackB := tags[a].track, tags[b].track
+		if (trackA == 0) != (trackB == 0) {
+			return trackB == 0
+		}
+		if trackA != trackB {
+			return trackA < trackB
+		}
+		if songs[a].Path() != songs[b].Path() {
+			return songs[a].Path() < songs[b].Path()
+		}
+		// tracks of the same cue sheet
+		startA, _, _ := songs[a].Bounds()
+		startB, _, _ := songs[b].Bounds()
+		return startA < startB
+	})
+}
+
+// Groups the indexes of the songs by the key, the groups and the songs inside
+// them are in the order they are found
+func groupSongs(
+	songs []*player.AudioFile, tags []songTags,
+	key func(*player.AudioFile, songTags) string,
+) [][]int {
+
+	var groups [][]int
+	indexes := make(map[string]int)
+
+	for i, song := range songs {
+		k := key(song, tags[i])
+		index, ok := indexes[k]
+		if !ok {
+			index = len(groups)
+			indexes[k] = index
+			groups = append(groups, nil)
+		}
+		groups[index] = append(groups[index], i)
+	}
+
+	return groups
+}
+
+// Spreads out the songs of each group. The next song is picked at random
+// from the groups other than the one just played, weighted by the number of
+// songs left in the group. A group holding more than half of the songs left
+// is picked first, so no two songs of a group are next to each other unless
+// the group has too many songs.
+func spreadOrder(r *rand.Rand, groups [][]int) []int {
+
+	var total int
+	for _, group := range groups {
+		r.Shuffle(len(group), func(i, j int) {
+			group[i], group[j] = group[j], group[i]
+		})
+		total += len(group)
+	}
+
+	order := make([]int, 0, total)
+	last := -1
+
+	for left := total; left > 0; left-- {
+
+		pick := -1
+		var candidates int
+
+		for g, group := range groups {
+			if g == last || len(group) == 0 {
+				continue
+			}
+			if 2*len(group) > left {
+				pick = g
+				break
+			}
+			candidates += len(group)
+		}
+
+		if pick < 0 && candidates == 0 {
+			// only the last group is left
+			pick = last
+		}
+
+		if pick < 0 {
+			n := r.Intn(candidates)
+			for g, group := range groups {
+				if g == last {
+					continue
+				}
+				if n < len(group) {
+					pick = g
+					break
+				}
+				n -= len(group)
+			}
+		}
+
+		order = append(order, groups[pick][0])
+		groups[pick] = groups[pick][1:]
+		last = pick
+	}
+
+	return order
+}
+
+// Shuffles the queue with the mode, the same seed gives the same order
+func (q *Queue) shuffleBy(mode shuffleMode, seed int64) {
+
+	before := make([]*player.AudioFile, len(q.items))
+	copy(before, q.items)
+
+	tags := make([]songTags, len(q.items))
+	if mode != shuffleRandom {
+		for i, item := range q.items {
+			if player.IsStream(item.Path()) || isCueTrack(item) {
+				continue
+			}
+			var err error
+			tags[i], err = getTags(item.Path())
+			if err != nil {
+				logError(err)
+			}
+		}
+	}
+
+	order := shuffleOrder(before, tags, mode, seed)
+	for i, index := range order {
+		q.items[i] = before[index]
+	}
+
+	q.lastShuffle = &lastShuffle{mode: mode, seed: seed, before: before}
+	q.redraw()
+}
+
+// Restores the order of the queue before the last shuffle. Songs removed since
+// then are left out and songs added since then stay at the end.
+func (q *Queue) unshuffle() bool {
+
+	if q.lastShuffle == nil {
+		return false
+	}
+
+	remaining := make(map[*player.AudioFile]int)
+	for _, item := range q.items {
+		remaining[item]++
+	}
+
+	var items []*player.AudioFile
+	for _, item := range q.lastShuffle.before {
+		if remaining[item] > 0 {
+			remaining[item]--
+			items = append(items, item)
+		}
+	}
+	for _, item := range q.items {
+		if remaining[item] > 0 {
+			remaining[item]--
+			items = append(items, item)
+		}
+	}
+
+	q.items = items
+	q.lastShuffle = nil
+	q.redraw()
+
+	return true
+}
+
+// Restores the last shuffle of the session, the songs which can no longer be
+// found are left out
+func (q *Queue) loadShuffle(finder *songFinder, shuffle sessionShuffle) {
+
+	mode, err := parseShuffleMode(shuffle.Mode)
+	if err != nil {
+		logError(err)
+		return
+	}
+
+	var before []*player.AudioFile
+	for _, song := range shuffle.Before {
+		if audioFile := finder.find(song); audioFile != nil {
+			before = append(before, audioFile)
+		}
+	}
+
+	q.lastShuffle = &lastShuffle{mode: mode, seed: shuffle.Seed, before: before}
+}
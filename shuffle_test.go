@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// returns songs with the artist and album tags, the path of the song is its
+// index
+func shuffleSongs(artists, albums []string) ([]*player.AudioFile, []songTags) {
+
+	songs := make([]*player.AudioFile, len(artists))
+	tags := make([]songTags, len(artists))
+
+	for i := range artists {
+		songs[i] = new(player.AudioFile)
+		songs[i].SetPath(fmt.Sprintf("/music/%d.mp3", i))
+		tags[i] = songTags{artist: artists[i], album: albums[i]}
+	}
+
+	return songs, tags
+}
+
+func TestShuffleOrder(t *testing.T) {
+
+	artists := []string{"a", "a", "a", "a", "b", "b", "c", "c"}
+	albums := []string{"x", "x", "y", "y", "z", "z", "w", "w"}
+	songs, tags := shuffleSongs(artists, albums)
+
+	for _, mode := range []shuffleMode{shuffleRandom, shuffleArtist, shuffleAlbum} {
+
+		order := shuffleOrder(songs, tags, mode, 42)
+
+		// every song is kept
+		sorted := append([]int(nil), order...)
+		sort.Ints(sorted)
+		assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, sorted, mode.String())
+
+		// the same seed gives the same order
+		assert.Equal(t, order, shuffleOrder(songs, tags, mode, 42), mode.String())
+	}
+
+	// songs of the same album stay together and in order
+	order := shuffleOrder(songs, tags, shuffleAlbum, 7)
+	for i := 0; i < len(order); i += 2 {
+		assert.Equal(t, order[i]+1, order[i+1])
+		assert.Equal(t, 0, order[i]%2)
+	}
+}
+
+func TestShuffleAlbumTracks(t *testing.T) {
+
+	songs, tags := shuffleSongs(
+		[]string{"a", "a", "a", "a"}, []string{"x", "x", "x", "x"})
+	tags[0].track = 3
+	tags[1].track = 1
+	tags[3].track = 2
+
+	// songs without track number go last
+	assert.Equal(t, []int{1, 3, 0, 2}, shuffleOrder(songs, tags, shuffleAlbum, 1))
+}
+
+func TestSpreadOrder(t *testing.T) {
+
+	// half of the songs are by the same artist
+	artists := []string{"a", "a", "a", "a", "b", "c", "d", "e"}
+	songs, tags := shuffleSongs(artists, make([]string, len(artists)))
+
+	for seed := int64(0); seed < 20; seed++ {
+		order := shuffleOrder(songs, tags, shuffleArtist, seed)
+		for i := 1; i < len(order); i++ {
+			sameArtist := artists[order[i]] == "a" && artists[order[i-1]] == "a"
+			assert.False(t, sameArtist, "seed %d: %v", seed, order)
+		}
+	}
+}
+
+func TestParseShuffleMode(t *testing.T) {
+
+	for _, name := range shuffleModes {
+		mode, err := parseShuffleMode(name)
+		assert.NoError(t, err)
+		assert.Equal(t, name, mode.String())
+	}
+
+	_, err := parseShuffleMode("shuffle")
+	assert.Error(t, err)
+}
+
+func TestUnshuffle(t *testing.T) {
+
+	prepareEqualizerTest(t)
+	gomu.colors = newColor()
+	gomu = prepareTest()
+
+	rapDir := gomu.playlist.GetRoot().GetChildren()[1]
+	gomu.playlist.addAllToQueue(rapDir)
+
+	items := gomu.queue.getItems()
+	assert.False(t, gomu.queue.unshuffle())
+
+	gomu.queue.shuffleBy(shuffleRandom, 3)
+	shuffled := gomu.queue.getItems()
+
+	assert.True(t, gomu.queue.unshuffle())
+	assert.Equal(t, items, gomu.queue.getItems())
+	assert.False(t, gomu.queue.unshuffle())
+
+	// the same seed gives the same order
+	gomu.queue.shuffleBy(shuffleRandom, 3)
+	assert.Equal(t, shuffled, gomu.queue.getItems())
+}
@@ -0,0 +1,302 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/ztrue/tracerr"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// sleep timer modes
+const (
+	sleepAfterDuration = iota
+	sleepAfterTrack
+	sleepAfterQueue
+)
+
+// inputs of sleep timer popup other than duration
+const (
+	sleepEndOfTrack = "end of track"
+	sleepEndOfQueue = "end of queue"
+	sleepOff        = "off"
+)
+
+// how long the volume is lowered before the player is paused
+const sleepFadeOut = 5 * time.Second
+
+// SleepTimer pauses the player with fade out when it expires
+type SleepTimer struct {
+	mu     sync.Mutex
+	active bool
+	mode   int
+	// when the timer expires in duration mode
+	deadline time.Time
+	// the last song to be played in queue mode, the timer ends with the
+	// current song if it is no longer in the queue
+	target *player.AudioFile
+	// length of the songs in the queue up to the target, see updateQueue
+	queued time.Duration
+	stop   chan struct{}
+	// closed once the countdown has returned
+	done chan struct{}
+}
+
+func newSleepTimer() *SleepTimer {
+	return &SleepTimer{}
+}
+
+// Parses input of sleep timer popup, plain number is treated as minutes
+func parseSleepTimer(input string) (mode int, d time.Duration, err error) {
+
+	input = strings.ToLower(strings.TrimSpace(input))
+
+	switch input {
+	case sleepEndOfTrack:
+		return sleepAfterTrack, 0, nil
+	case sleepEndOfQueue:
+		return sleepAfterQueue, 0, nil
+	}
+
+	if minutes, err := strconv.Atoi(input); err == nil {
+		d = time.Duration(minutes) * time.Minute
+	} else if d, err = time.ParseDuration(input); err != nil {
+		return 0, 0, tracerr.Errorf("invalid sleep timer: %s", input)
+	}
+
+	if d <= 0 {
+		return 0, 0, tracerr.Errorf("invalid sleep timer: %s", input)
+	}
+
+	return sleepAfterDuration, d, nil
+}
+
+// Starts the timer, replacing the previous one
+func (s *SleepTimer) start(mode int, d time.Duration) {
+
+	s.cancel()
+
+	s.mu.Lock()
+	s.active = true
+	s.mode = mode
+	s.deadline = time.Now().Add(d)
+	s.target = nil
+	if mode == sleepAfterQueue && len(gomu.queue.items) > 0 {
+		s.target = gomu.queue.items[len(gomu.queue.items)-1]
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	stop, done := s.stop, s.done
+	s.mu.Unlock()
+
+	s.updateQueue(gomu.queue.items)
+
+	go s.run(stop, done, mode)
+}
+
+// Stops the timer without pausing the player, it returns once the countdown
+// has stopped
+func (s *SleepTimer) cancel() {
+
+	s.mu.Lock()
+
+	if !s.active {
+		s.mu.Unlock()
+		return
+	}
+
+	s.active = false
+	close(s.stop)
+	done := s.done
+	s.mu.Unlock()
+
+	<-done
+}
+
+// Ends the timer from the countdown when it expires, unlike cancel it does not
+// wait for the countdown
+func (s *SleepTimer) expire(stop chan struct{}) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the timer has been cancelled or started again in the meantime
+	if !s.active || s.stop != stop {
+		return
+	}
+
+	s.active = false
+	close(stop)
+}
+
+// Updates the length of the songs to be played before the timer expires in
+// queue mode. It must be called from the UI goroutine whenever the queue
+// changes.
+func (s *SleepTimer) updateQueue(items []*player.AudioFile) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queued = 0
+	if s.mode != sleepAfterQueue || s.target == nil {
+		return
+	}
+
+	var queued time.Duration
+	for _, item := range items {
+		queued += item.Len()
+		if item == s.target {
+			s.queued = queued
+			return
+		}
+	}
+}
+
+// Checks if the timer is running
+func (s *SleepTimer) isActive() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active
+}
+
+// Returns the time left until the player is paused, zero if the timer is not
+// running
+func (s *SleepTimer) remaining() time.Duration {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.active {
+		return 0
+	}
+
+	if s.mode == sleepAfterDuration {
+		remaining := time.Until(s.deadline)
+		if remaining < 0 {
+			return 0
+		}
+		return remaining
+	}
+
+	var remaining time.Duration
+	if gomu.player.IsRunning() || gomu.player.IsPaused() {
+		remaining = gomu.player.GetLength() - gomu.player.GetPosition()
+	}
+
+	if s.mode == sleepAfterQueue {
+		remaining += s.queued
+	}
+
+	if remaining < 0 {
+		return 0
+	}
+
+	// the song plays faster or slower than real time
+	return time.Duration(float64(remaining) / gomu.player.GetSpeed())
+}
+
+// Counts down and fades out the player when the timer expires
+func (s *SleepTimer) run(stop, done chan struct{}, mode int) {
+
+	defer close(done)
+
+	// cancel waits for the countdown, so it must not wait for the UI
+	updateTitle := func() {
+		go gomu.app.QueueUpdateDraw(func() {
+			gomu.queue.updateTitle()
+		})
+	}
+
+	for {
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(time.Second):
+		}
+
+		remaining := s.remaining()
+
+		// songs are faded out until their very end
+		fade := sleepFadeOut
+		if mode != sleepAfterDuration && remaining < fade {
+			fade = remaining
+		}
+
+		if remaining > 0 && (mode == sleepAfterDuration || remaining > fade) {
+			updateTitle()
+			continue
+		}
+
+		s.expire(stop)
+		gomu.player.FadeOut(fade)
+		updateTitle()
+		return
+	}
+}
+
+// Input popup to set the sleep timer
+func sleepTimerPopup() {
+
+	popupID := "sleep-timer-input-popup"
+	input := newInputPopup(popupID, " Sleep Timer ", "Duration: ", "")
+
+	options := []string{sleepEndOfTrack, sleepEndOfQueue, sleepOff}
+	input.SetAutocompleteFunc(func(currentText string) []string {
+
+		if currentText == "" {
+			return nil
+		}
+
+		var entries []string
+		for _, option := range options {
+			if strings.HasPrefix(option, strings.ToLower(currentText)) {
+				entries = append(entries, option)
+			}
+		}
+
+		return entries
+	})
+
+	input.SetDoneFunc(func(key tcell.Key) {
+
+		switch key {
+		case tcell.KeyEnter:
+
+			text := strings.TrimSpace(input.GetText())
+			if text == "" {
+				return
+			}
+
+			gomu.pages.RemovePage(popupID)
+			gomu.popups.pop()
+
+			if strings.EqualFold(text, sleepOff) {
+				gomu.sleepTimer.cancel()
+				gomu.queue.updateTitle()
+				defaultTimedPopup(" Sleep Timer ", "Sleep timer is turned off")
+				return
+			}
+
+			mode, d, err := parseSleepTimer(text)
+			if err != nil {
+				errorPopup(err)
+				return
+			}
+
+			gomu.sleepTimer.start(mode, d)
+			gomu.queue.updateTitle()
+			defaultTimedPopup(" Sleep Timer ", "Pausing after "+text)
+
+		case tcell.KeyEscape:
+			gomu.pages.RemovePage(popupID)
+			gomu.popups.pop()
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+func TestParseSleepTimer(t *testing.T) {
+
+	tests := []struct {
+		input string
+		mode  int
+		d     time.Duration
+	}{
+		{"30m", sleepAfterDuration, 30 * time.Minute},
+		{"1h30m", sleepAfterDuration, 90 * time.Minute},
+		{" 45 ", sleepAfterDuration, 45 * time.Minute},
+		{"End of Track", sleepAfterTrack, 0},
+		{"end of queue", sleepAfterQueue, 0},
+	}
+
+	for _, test := range tests {
+		mode, d, err := parseSleepTimer(test.input)
+		assert.NoError(t, err, test.input)
+		assert.Equal(t, test.mode, mode, test.input)
+		assert.Equal(t, test.d, d, test.input)
+	}
+
+	for _, input := range []string{"", "soon", "-5m", "0"} {
+		_, _, err := parseSleepTimer(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestSleepTimer(t *testing.T) {
+
+	gomu = newGomu()
+	gomu.app = tview.NewApplication()
+	gomu.player = player.New(0)
+	gomu.queue = &Queue{List: tview.NewList()}
+
+	s := gomu.sleepTimer
+	assert.False(t, s.isActive())
+	assert.Equal(t, time.Duration(0), s.remaining())
+
+	s.start(sleepAfterDuration, time.Hour)
+	assert.True(t, s.isActive())
+	assert.InDelta(t, time.Hour, s.remaining(), float64(time.Second))
+
+	s.cancel()
+	assert.False(t, s.isActive())
+	assert.Equal(t, time.Duration(0), s.remaining())
+
+	// a song in the queue is played before the player is paused
+	song := new(player.AudioFile)
+	song.SetLen(3 * time.Minute)
+	gomu.queue.items = []*player.AudioFile{song}
+
+	s.start(sleepAfterQueue, 0)
+	assert.Equal(t, 3*time.Minute, s.remaining())
+
+	// the timer ends with the current song once the last song is removed
+	s.updateQueue(nil)
+	assert.Equal(t, time.Duration(0), s.remaining())
+	assert.True(t, s.isActive())
+	s.cancel()
+}
@@ -0,0 +1,210 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ztrue/tracerr"
+)
+
+// snapshots are saved as json files named after the snapshot
+const snapshotExt = ".json"
+
+// queueSnapshot is the queue saved under a name, the songs are saved like the
+// queue of the session
+type queueSnapshot struct {
+	Version int           `json:"version"`
+	Queue   []sessionSong `json:"queue"`
+}
+
+// Gets the directory of the queue snapshots, it is next to the queue cache
+func snapshotDir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		logError(err)
+	}
+	return filepath.Join(cacheDir, "gomu", "queues")
+}
+
+// Gets the path of the snapshot, the name must be usable as a file name
+func snapshotPath(name string) (string, error) {
+
+	name = strings.TrimSpace(name)
+
+	if name == "" || name == "." || name == ".." ||
+		strings.ContainsAny(name, `/\`) {
+		return "", tracerr.Errorf("invalid queue name %q", name)
+	}
+
+	return filepath.Join(snapshotDir(), name+snapshotExt), nil
+}
+
+// Lists the names of saved queues in alphabetical order
+func listSnapshots() ([]string, error) {
+
+	files, err := ioutil.ReadDir(snapshotDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	var names []string
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != snapshotExt {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(file.Name(), snapshotExt))
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Saves the queue under the name, the queue saved under the same name is
+// replaced
+func (q *Queue) saveSnapshot(name string) error {
+
+	snapshotPath, err := snapshotPath(name)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	snapshot := queueSnapshot{
+		Version: sessionVersion,
+		Queue:   []sessionSong{},
+	}
+	for _, item := range q.items {
+		snapshot.Queue = append(snapshot.Queue, newSessionSong(item))
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "\t")
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(snapshotPath), 0755)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	err = ioutil.WriteFile(snapshotPath, data, 0644)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// Replaces the queue with the saved queue, returns the number of songs which
+// could not be found or read
+func (q *Queue) loadSnapshot(name string) (int, error) {
+
+	snapshotPath, err := snapshotPath(name)
+	if err != nil {
+		return 0, tracerr.Wrap(err)
+	}
+
+	data, err := ioutil.ReadFile(snapshotPath)
+	if err != nil {
+		return 0, tracerr.Wrap(err)
+	}
+
+	var snapshot queueSnapshot
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return 0, tracerr.Wrap(err)
+	}
+
+	q.clearQueue()
+
+	finder := newSongFinder(gomu.playlist.GetRoot())
+	var missing int
+
+	for _, song := range snapshot.Queue {
+
+		audioFile := finder.find(song)
+		if audioFile == nil {
+			missing++
+			continue
+		}
+
+		// the song is left out of the queue when it can't be read
+		_, err := q.enqueue(audioFile)
+		if err != nil {
+			logError(err)
+			missing++
+		}
+	}
+
+	return missing, nil
+}
+
+// Renames the saved queue, the name must not be taken by another queue
+func renameSnapshot(oldName, newName string) error {
+
+	oldPath, err := snapshotPath(oldName)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	newPath, err := snapshotPath(newName)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	if oldPath == newPath {
+		return nil
+	}
+
+	if _, err := os.Stat(newPath); err == nil {
+		return tracerr.Errorf("queue %q already exists", strings.TrimSpace(newName))
+	}
+
+	err = os.Rename(oldPath, newPath)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// Deletes the saved queue
+func deleteSnapshot(name string) error {
+
+	snapshotPath, err := snapshotPath(name)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	err = os.Remove(snapshotPath)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// Lists the saved queues and calls the handler with the selected one
+func snapshotPopup(title string, handler func(name string)) {
+
+	names, err := listSnapshots()
+	if err != nil {
+		errorPopup(err)
+		return
+	}
+
+	if len(names) == 0 {
+		defaultTimedPopup(title, "No saved queues")
+		return
+	}
+
+	searchPopup(title, names, handler)
+}
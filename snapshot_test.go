@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+func TestSnapshotPath(t *testing.T) {
+
+	for _, name := range []string{"", " ", "..", "a/b", `a\b`} {
+		_, err := snapshotPath(name)
+		assert.Error(t, err, name)
+	}
+
+	_, err := snapshotPath("gym")
+	assert.NoError(t, err)
+}
+
+func TestSnapshot(t *testing.T) {
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	prepareEqualizerTest(t)
+	gomu.colors = newColor()
+	gomu = prepareTest()
+
+	names, err := listSnapshots()
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+
+	songs := gomu.playlist.GetRoot().GetChildren()[1].GetChildren()
+	for _, node := range songs[:2] {
+		_, err := gomu.queue.enqueue(node.GetReference().(*player.AudioFile))
+		assert.NoError(t, err)
+	}
+	expected := queuePaths()
+
+	assert.NoError(t, gomu.queue.saveSnapshot("work"))
+	assert.NoError(t, gomu.queue.saveSnapshot("gym"))
+
+	names, err = listSnapshots()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gym", "work"}, names)
+
+	assert.Error(t, renameSnapshot("gym", "work"))
+	assert.NoError(t, renameSnapshot("gym", "party"))
+	assert.NoError(t, deleteSnapshot("work"))
+
+	names, err = listSnapshots()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"party"}, names)
+
+	gomu.queue.clearQueue()
+	missing, err := gomu.queue.loadSnapshot("party")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, missing)
+	assert.Equal(t, expected, queuePaths())
+
+	_, err = gomu.queue.loadSnapshot("work")
+	assert.Error(t, err)
+}
+
+func TestSnapshotUnreadable(t *testing.T) {
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	prepareEqualizerTest(t)
+	gomu.colors = newColor()
+	gomu = prepareTest()
+
+	song := gomu.playlist.GetRoot().GetChildren()[1].GetChildren()[0].
+		GetReference().(*player.AudioFile)
+
+	// song in the playlist which can't be read
+	brokenPath := filepath.Join(dir, "broken.mp3")
+	err := ioutil.WriteFile(brokenPath, []byte("not audio"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken := new(player.AudioFile)
+	broken.SetName("broken")
+	broken.SetPath(brokenPath)
+	broken.SetIsAudioFile(true)
+	gomu.playlist.GetRoot().AddChild(tview.NewTreeNode("broken").SetReference(broken))
+
+	snapshot := queueSnapshot{
+		Version: sessionVersion,
+		Queue:   []sessionSong{newSessionSong(broken), newSessionSong(song)},
+	}
+	data, err := json.Marshal(snapshot)
+	assert.NoError(t, err)
+
+	snapshotPath, err := snapshotPath("mix")
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(snapshotPath), 0755))
+	assert.NoError(t, ioutil.WriteFile(snapshotPath, data, 0644))
+
+	missing, err := gomu.queue.loadSnapshot("mix")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, missing)
+	assert.Equal(t, []string{song.Path()}, queuePaths())
+	assert.Equal(t, len(gomu.queue.items), gomu.queue.GetItemCount())
+}
@@ -10,7 +10,6 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strings"
-	"sync"
 	"syscall"
 
 	"github.com/gdamore/tcell/v2"
@@ -34,10 +33,12 @@ type Panel interface {
 
 // Args is the args for gomu executable
 type Args struct {
-	config  *string
-	empty   *bool
-	music   *string
-	version *bool
+	config       *string
+	empty        *bool
+	music        *string
+	version      *bool
+	scanLoudness *string
+	output       *string
 }
 
 func getArgs() Args {
@@ -55,12 +56,16 @@ func getArgs() Args {
 	musicPath := filepath.Join(home, "Music")
 	musicFlag := flag.String("music", musicPath, "Specify music directory")
 	versionFlag := flag.Bool("version", false, "Print gomu version")
+	scanLoudnessFlag := flag.String("scan-loudness", "", "Write ReplayGain tags to mp3 files in directory and exit")
+	outputFlag := flag.String("output", "", "Specify audio output: speaker, null or path of wav file")
 	flag.Parse()
 	return Args{
-		config:  configFlag,
-		empty:   emptyFlag,
-		music:   musicFlag,
-		version: versionFlag,
+		config:       configFlag,
+		empty:        emptyFlag,
+		music:        musicFlag,
+		version:      versionFlag,
+		scanLoudness: scanLoudnessFlag,
+		output:       outputFlag,
 	}
 }
 
@@ -100,9 +105,80 @@ func defineInternals() {
 		item := gomu.queue.items[index]
 		return item
 	})
+	// "off", "all" or "one"
+	queue.Define("repeat_mode", func() string {
+		return gomu.queue.repeat.String()
+	})
+	queue.Define("set_repeat_mode", func(mode string) error {
+		m, err := parseRepeatMode(mode)
+		if err != nil {
+			return err
+		}
+		gomu.queue.setRepeat(m)
+		return nil
+	})
+	// "random", "artist" or "album", the same seed gives the same order
+	queue.Define("shuffle", func(mode string, seed int64) error {
+		m, err := parseShuffleMode(mode)
+		if err != nil {
+			return err
+		}
+		gomu.queue.record("shuffle", func() {
+			gomu.queue.shuffleBy(m, seed)
+		})
+		return nil
+	})
+	queue.Define("shuffle_seed", func() int64 {
+		if gomu.queue.lastShuffle == nil {
+			return 0
+		}
+		return gomu.queue.lastShuffle.seed
+	})
+	queue.Define("stop_after_current", func() bool {
+		return gomu.queue.stopAfterCurrent
+	})
+	queue.Define("set_stop_after_current", func(stop bool) {
+		if stop != gomu.queue.stopAfterCurrent {
+			gomu.queue.toggleStopAfterCurrent()
+		}
+	})
+	// edits made by scripts can be undone like the edits made with keys
+	queue.Define("enqueue", func(audioFile *player.AudioFile) error {
+		var err error
+		gomu.queue.record("add", func() {
+			_, err = gomu.queue.enqueue(audioFile)
+		})
+		return err
+	})
+	queue.Define("remove", func(index int) error {
+		var err error
+		gomu.queue.record("delete", func() {
+			_, err = gomu.queue.deleteItem(index)
+		})
+		return err
+	})
+	queue.Define("clear", func() {
+		gomu.queue.record("clear", gomu.queue.clearQueue)
+	})
+	queue.Define("undo", func() bool {
+		_, ok := gomu.queue.undo()
+		return ok
+	})
+	queue.Define("redo", func() bool {
+		_, ok := gomu.queue.redo()
+		return ok
+	})
 
 	player, _ := gomu.anko.NewModule("Player")
 	player.Define("current_audio", gomu.player.GetCurrentSong)
+	// title of the song played by internet radio
+	player.Define("stream_title", gomu.player.GetStreamTitle)
+	// "playing", "paused" or "stopped"
+	player.Define("state", func() string {
+		return gomu.player.State().String()
+	})
+	// zero when the sleep timer is not running
+	player.Define("sleep_timer_remaining", gomu.sleepTimer.remaining)
 }
 
 func setupHooks(hook *hook.EventHook, anko *anko.Anko) {
@@ -229,11 +305,16 @@ module General {
 	# confirmation popup to add the whole playlist to the queue
 	confirm_bulk_add    = true
 	confirm_on_exit     = true
+	# what to do after a song finishes: "off", "all" or "one"
+	repeat_mode         = "off"
+	# same as repeat_mode = "all", kept for older config files
 	queue_loop          = false
 	load_prev_queue     = true
+	# number of queue edits which can be undone
+	undo_depth          = 50
 	popup_timeout       = "5s"
 	sort_by_mtime       = false
-	# change this to directory that contains mp3 files
+	# change this to directory that contains audio files (mp3, flac, ogg, wav)
 	music_dir           = "~/Music"
 	# url history of downloaded audio will be saved here
 	history_path        = "~/.local/share/gomu/urls"
@@ -242,6 +323,18 @@ module General {
 	use_emoji           = true
 	# initial volume when gomu starts up
 	volume              = 80
+	# mix the end of a song with the start of the next song, eg. "3s"
+	crossfade           = "0s"
+	# loudness normalization using replaygain tags: "track", "album" or "off"
+	replaygain          = "off"
+	# songs longer than this continue from where they were left off, eg. podcasts
+	# and audiobooks, "0s" always plays from the start
+	resume_threshold    = "20m"
+	# show spectrum of the song in the playing bar instead of album photo
+	visualizer          = false
+	# where the audio is played: "speaker", "null" to discard it or path of
+	# wav file to record it
+	output              = "speaker"
 	# if you experiencing error using this invidious instance, you can change it
 	# to another instance from this list:
 	# https://github.com/iv-org/documentation/blob/master/Invidious-Instances.md
@@ -255,6 +348,25 @@ module General {
 	rename_bytag        = false
 }
 
+module Equalizer {
+	# name of the preset to use, leave it empty to use the gains of bands
+	preset  = "flat"
+	# gain in dB from -12 to 12 of the 31, 62, 125, 250, 500, 1k, 2k, 4k, 8k and
+	# 16k Hz bands
+	bands   = [0, 0, 0, 0, 0, 0, 0, 0, 0, 0]
+	# you can add your own preset here and select it by pressing 'e'
+	presets = {
+		"flat":         [0, 0, 0, 0, 0, 0, 0, 0, 0, 0],
+		"rock":         [5, 4, 3, 1, -1, -1, 1, 3, 4, 5],
+		"pop":          [-1, 1, 3, 4, 3, 0, -1, -1, 1, 2],
+		"jazz":         [3, 2, 1, 2, -1, -1, 0, 1, 2, 3],
+		"classical":    [4, 3, 2, 1, -1, -1, 0, 2, 3, 4],
+		"vocal":        [-2, -1, 0, 2, 4, 4, 3, 1, 0, -1],
+		"bass_boost":   [6, 5, 4, 2, 0, 0, 0, 0, 0, 0],
+		"treble_boost": [0, 0, 0, 0, 0, 0, 2, 4, 5, 6],
+	}
+}
+
 module Emoji {
 	# default emoji here is using awesome-terminal-fonts
 	# you can change these to your liking
@@ -262,6 +374,7 @@ module Emoji {
 	file         = ""
 	loop         = "ﯩ"
 	noloop       = ""
+	repeat_one   = "ﯩ1"
 }
 
 module Color {
@@ -275,6 +388,7 @@ module Color {
 	playlist_highlight = "darkcyan"
 
 	queue_highlight    = "darkcyan"
+	queue_mark         = "yellow"
 
 	now_playing_title = "darkgreen"
 	subtitle          = "darkgoldenrod"
@@ -315,6 +429,45 @@ module Color {
 	return nil
 }
 
+// Applies player settings from config file, this can be done again after
+// reloading the config file
+func configurePlayer() {
+
+	gomu.player.SetCrossfade(getCrossfade())
+
+	err := gomu.player.SetReplayGain(gomu.anko.GetString("General.replaygain"))
+	if err != nil {
+		logError(err)
+	}
+}
+
+// Sets the audio output of the player, the flag takes precedence over config
+// file
+func setOutput(name string) error {
+
+	if name == "" {
+		name = gomu.anko.GetString("General.output")
+	}
+
+	if strings.HasPrefix(name, "wav:") {
+		name = "wav:" + expandTilde(strings.TrimPrefix(name, "wav:"))
+	} else if strings.EqualFold(filepath.Ext(name), ".wav") {
+		name = expandTilde(name)
+	}
+
+	output, err := player.NewOutput(name)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	err = gomu.player.SetOutput(output)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
 // Sets the layout of the application
 func layout(gomu *Gomu) *tview.Flex {
 	flex := tview.NewFlex().
@@ -335,6 +488,15 @@ func start(application *tview.Application, args Args) {
 		return
 	}
 
+	// Scan loudness and exit
+	if *args.scanLoudness != "" {
+		err := scanLoudnessDir(expandFilePath(*args.scanLoudness))
+		if err != nil {
+			die(err)
+		}
+		return
+	}
+
 	// Assigning to global variable gomu
 	gomu = newGomu()
 	gomu.command.defineCommands()
@@ -369,66 +531,7 @@ func start(application *tview.Application, args Args) {
 	gomu.initPanels(application, args)
 	defineInternals()
 
-	gomu.player.SetSongStart(func(audio player.Audio) {
-
-		duration, err := getTagLength(audio.Path())
-		if err != nil || duration == 0 {
-			duration, err = player.GetLength(audio.Path())
-			if err != nil {
-				logError(err)
-				return
-			}
-		}
-
-		audioFile := audio.(*player.AudioFile)
-
-		gomu.playingBar.newProgress(audioFile, int(duration.Seconds()))
-
-		name := audio.Name()
-		var description string
-
-		if len(gomu.playingBar.subtitles) == 0 {
-			description = name
-		} else {
-			lang := gomu.playingBar.subtitle.LangExt
-
-			description = fmt.Sprintf("%s \n\n %s lyric loaded", name, lang)
-		}
-
-		defaultTimedPopup(" Now Playing ", description)
-
-		go func() {
-			err := gomu.playingBar.run()
-			if err != nil {
-				logError(err)
-			}
-		}()
-
-	})
-
-	gomu.player.SetSongFinish(func(currAudio player.Audio) {
-
-		gomu.playingBar.subtitles = nil
-		var mu sync.Mutex
-		mu.Lock()
-		gomu.playingBar.subtitle = nil
-		mu.Unlock()
-		if gomu.queue.isLoop {
-			_, err = gomu.queue.enqueue(currAudio.(*player.AudioFile))
-			if err != nil {
-				logError(err)
-			}
-		}
-
-		if len(gomu.queue.items) > 0 {
-			err := gomu.queue.playQueue()
-			if err != nil {
-				logError(err)
-			}
-		} else {
-			gomu.playingBar.setDefault()
-		}
-	})
+	go handlePlayerEvents(gomu.player.Subscribe())
 
 	flex := layout(gomu)
 	gomu.pages.AddPage("main", flex, true, true)
@@ -439,14 +542,38 @@ func start(application *tview.Application, args Args) {
 
 	gomu.playingBar.setDefault()
 
-	gomu.queue.isLoop = gomu.anko.GetBool("General.queue_loop")
+	err = loadSession()
+	if err != nil {
+		errorPopup(err)
+	}
+	configurePlayer()
+
+	if gomu.anko.GetBool("General.visualizer") {
+		gomu.playingBar.setVisualizer(true)
+	}
+	go gomu.playingBar.visualize()
+
+	err = setOutput(*args.output)
+	if err != nil {
+		die(err)
+	}
+
+	err = loadEqualizer()
+	if err != nil {
+		logError(err)
+	}
+
+	gomu.positions.threshold = getResumeThreshold()
+	if err := gomu.positions.load(); err != nil {
+		logError(err)
+	}
 
 	loadQueue := gomu.anko.GetBool("General.load_prev_queue")
 
 	if !*args.empty && loadQueue {
 		// load saved queue from previous session
 		if err := gomu.queue.loadQueue(); err != nil {
-			logError(err)
+			errorPopup(err)
 		}
 	}
 
@@ -476,6 +603,7 @@ func start(application *tview.Application, args Args) {
 		'-': "volume_down",
 		'_': "volume_down",
 		'n': "skip",
+		'N': "prev",
 		':': "command_search",
 		'?': "toggle_help",
 		'f': "forward",
@@ -485,6 +613,17 @@ func start(application *tview.Application, args Args) {
 		'm': "repl",
 		'T': "switch_lyric",
 		'c': "show_colors",
+		'e': "equalizer",
+		'>': "speed_up",
+		'<': "speed_down",
+		'[': "loop_a",
+		']': "loop_b",
+		'|': "clear_loop",
+		'S': "sleep_timer",
+		'v': "toggle_visualizer",
+		')': "next_chapter",
+		'(': "prev_chapter",
+		'C': "chapters",
 	}
 
 	for key, cmdName := range cmds {
@@ -0,0 +1,134 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rivo/tview"
+	"github.com/ztrue/tracerr"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// name of the playlist node which contains the stations
+const stationsNodeName = "Radio"
+
+// station is internet radio or any http audio stream
+type station struct {
+	name string
+	url  string
+}
+
+// Gets path of the stations file
+func stationsPath() string {
+	cfd, err := os.UserConfigDir()
+	if err != nil {
+		logError(tracerr.Wrap(err))
+	}
+	return filepath.Join(cfd, "gomu", "stations")
+}
+
+// Parses stations, each line is the url of the stream optionally followed by
+// the name of the station. Empty lines and lines starting with # are ignored.
+func parseStations(r io.Reader) ([]station, error) {
+
+	var stations []station
+	scanner := bufio.NewScanner(r)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		url := fields[0]
+
+		if !player.IsStream(url) {
+			return nil, tracerr.Errorf("invalid station url at line %d: %s", lineNumber, url)
+		}
+
+		name := strings.Join(fields[1:], " ")
+		if name == "" {
+			name = url
+		}
+
+		stations = append(stations, station{name: name, url: url})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, tracerr.Wrap(err)
+	}
+
+	return stations, nil
+}
+
+// Adds the stations from stations file as a playlist of root, nothing is added
+// if the file does not exist
+func addStations(root *tview.TreeNode, stationsFile string) error {
+
+	f, err := os.Open(stationsFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer f.Close()
+
+	stations, err := parseStations(f)
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	node := tview.NewTreeNode(stationsNodeName)
+
+	stationsFile = filepath.Clean(stationsFile)
+
+	playlist := new(player.AudioFile)
+	playlist.SetName(stationsNodeName)
+	playlist.SetPath(stationsFile)
+	playlist.SetIsAudioFile(false)
+	playlist.SetNode(node)
+	playlist.SetParentNode(root)
+
+	node.SetReference(playlist)
+	node.SetColor(gomu.colors.playlistDir)
+	node.SetText(setDisplayText(playlist))
+	root.AddChild(node)
+
+	for _, s := range stations {
+
+		child := tview.NewTreeNode(s.name)
+
+		audioFile := new(player.AudioFile)
+		audioFile.SetName(s.name)
+		audioFile.SetPath(s.url)
+		audioFile.SetIsAudioFile(true)
+		audioFile.SetNode(child)
+		audioFile.SetParentNode(node)
+
+		child.SetReference(audioFile)
+		child.SetText(setDisplayText(audioFile))
+		node.AddChild(child)
+	}
+
+	return nil
+}
+
+// Checks if the audio file is a station or the playlist of the stations, they
+// are not files that can be modified
+func isStation(audioFile *player.AudioFile) bool {
+
+	if audioFile == nil {
+		return false
+	}
+
+	return player.IsStream(audioFile.Path()) ||
+		audioFile.Path() == filepath.Clean(stationsPath())
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStations(t *testing.T) {
+
+	const stations = `
+# chill
+https://ice1.somafm.com/groovesalad-128-mp3   Groove Salad
+http://localhost:8000/stream
+`
+
+	got, err := parseStations(strings.NewReader(stations))
+	assert.NoError(t, err)
+	assert.Equal(t, []station{
+		{name: "Groove Salad", url: "https://ice1.somafm.com/groovesalad-128-mp3"},
+		{name: "http://localhost:8000/stream", url: "http://localhost:8000/stream"},
+	}, got)
+
+	_, err = parseStations(strings.NewReader("Groove Salad https://ice1.somafm.com"))
+	assert.Error(t, err)
+}
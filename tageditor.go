@@ -29,6 +29,11 @@ type lyricFlex struct {
 func tagPopup(node *player.AudioFile) (err error) {
 
 	popupID := "tag-editor-input-popup"
+
+	if format, ok := player.FormatOf(node.Path()); ok && format != "mp3" {
+		return tracerr.Errorf("unable to edit tags of %s file", format)
+	}
+
 	tag, popupLyricMap, options, err := node.LoadTagMap()
 	if err != nil {
 		return tracerr.Wrap(err)
@@ -0,0 +1,134 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"github.com/issadarkthing/gomu/player"
+)
+
+// number of queue edits kept when undo_depth is not set
+const defaultUndoDepth = 50
+
+// queueEdit is a change of the queue, the queue is put back to either side of
+// the change to undo or redo it
+type queueEdit struct {
+	name   string
+	before []*player.AudioFile
+	after  []*player.AudioFile
+}
+
+// Gets the number of queue edits which can be undone
+func getUndoDepth() int {
+	depth := gomu.anko.GetInt("General.undo_depth")
+	if depth <= 0 {
+		return defaultUndoDepth
+	}
+	return depth
+}
+
+func copyItems(items []*player.AudioFile) []*player.AudioFile {
+	return append([]*player.AudioFile{}, items...)
+}
+
+func sameItems(a, b []*player.AudioFile) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Runs the edit and records it so it can be undone, nothing is recorded if the
+// queue is left unchanged. Recording a new edit drops the edits to redo.
+func (q *Queue) record(name string, edit func()) {
+
+	before := copyItems(q.items)
+	edit()
+
+	if sameItems(before, q.items) {
+		return
+	}
+
+	q.undoLog = append(q.undoLog, queueEdit{
+		name:   name,
+		before: before,
+		after:  copyItems(q.items),
+	})
+
+	depth := q.undoDepth
+	if depth <= 0 {
+		depth = defaultUndoDepth
+	}
+	if len(q.undoLog) > depth {
+		q.undoLog = q.undoLog[len(q.undoLog)-depth:]
+	}
+
+	q.redoLog = nil
+}
+
+// Puts the queue back to the state before the last edit, returns the name of
+// the edit or false if there is nothing to undo
+func (q *Queue) undo() (string, bool) {
+
+	if len(q.undoLog) == 0 {
+		return "", false
+	}
+
+	edit := q.undoLog[len(q.undoLog)-1]
+	q.undoLog = q.undoLog[:len(q.undoLog)-1]
+	q.redoLog = append(q.redoLog, edit)
+
+	q.setItems(edit.before)
+
+	return edit.name, true
+}
+
+// Applies the last undone edit again, returns the name of the edit or false if
+// there is nothing to redo
+func (q *Queue) redo() (string, bool) {
+
+	if len(q.redoLog) == 0 {
+		return "", false
+	}
+
+	edit := q.redoLog[len(q.redoLog)-1]
+	q.redoLog = q.redoLog[:len(q.redoLog)-1]
+	q.undoLog = append(q.undoLog, edit)
+
+	q.setItems(edit.after)
+
+	return edit.name, true
+}
+
+// Replaces the songs of the queue, the song opened ahead by the player is
+// updated as well
+func (q *Queue) setItems(items []*player.AudioFile) {
+	q.items = copyItems(items)
+	q.clearSelection()
+	q.redraw()
+}
+
+// Removes the song taken out of the queue to be played from the recorded
+// edits, so undo does not bring back the songs which have been played
+func (q *Queue) forgetPlayed(audioFile *player.AudioFile) {
+
+	forget := func(items []*player.AudioFile) []*player.AudioFile {
+		for i, item := range items {
+			if item == audioFile {
+				return append(copyItems(items[:i]), items[i+1:]...)
+			}
+		}
+		return items
+	}
+
+	for _, log := range [][]queueEdit{q.undoLog, q.redoLog} {
+		for i := range log {
+			log[i].before = forget(log[i].before)
+			log[i].after = forget(log[i].after)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+func TestQueueUndo(t *testing.T) {
+
+	songs := prepareSelectionTest(t)
+	q := gomu.queue
+
+	q.record("delete", func() {
+		q.deleteItem(1)
+	})
+	q.record("clear", q.clearQueue)
+	assert.Empty(t, q.items)
+
+	name, ok := q.undo()
+	assert.True(t, ok)
+	assert.Equal(t, "clear", name)
+	assert.Equal(t, []*player.AudioFile{songs[0], songs[2]}, q.items)
+	assert.Equal(t, 2, q.GetItemCount())
+
+	name, ok = q.undo()
+	assert.True(t, ok)
+	assert.Equal(t, "delete", name)
+	assert.Equal(t, songs, q.items)
+
+	_, ok = q.undo()
+	assert.False(t, ok)
+
+	name, ok = q.redo()
+	assert.True(t, ok)
+	assert.Equal(t, "delete", name)
+	assert.Equal(t, []*player.AudioFile{songs[0], songs[2]}, q.items)
+
+	// a new edit drops the edits to redo
+	q.record("move", func() {
+		q.swapItems(0, 1)
+	})
+	_, ok = q.redo()
+	assert.False(t, ok)
+
+	// edits which leave the queue unchanged are not recorded
+	q.record("shuffle", func() {})
+	name, _ = q.undo()
+	assert.Equal(t, "move", name)
+}
+
+func TestQueueUndoDepth(t *testing.T) {
+
+	songs := prepareSelectionTest(t)
+	q := gomu.queue
+	q.undoDepth = 2
+
+	for range songs {
+		q.record("delete", func() {
+			q.deleteItem(0)
+		})
+	}
+
+	_, ok := q.undo()
+	assert.True(t, ok)
+	_, ok = q.undo()
+	assert.True(t, ok)
+	_, ok = q.undo()
+	assert.False(t, ok)
+
+	assert.Equal(t, songs[1:], q.items)
+}
+
+func TestQueueForgetPlayed(t *testing.T) {
+
+	songs := prepareSelectionTest(t)
+	q := gomu.queue
+
+	q.record("delete", func() {
+		q.deleteItem(2)
+	})
+
+	// songs taken out of the queue to be played do not come back on undo
+	played, err := q.dequeue()
+	assert.NoError(t, err)
+	assert.Equal(t, songs[0], played)
+
+	_, ok := q.undo()
+	assert.True(t, ok)
+	assert.Equal(t, songs[1:], q.items)
+}
+
+func TestRepeatSongUndo(t *testing.T) {
+
+	songs := prepareSelectionTest(t)
+	q := gomu.queue
+
+	played, err := q.dequeue()
+	assert.NoError(t, err)
+
+	// the song put back by repeat all can be undone like the other edits
+	repeatSong(played)
+	assert.Equal(t, append(songs[1:], played), q.items)
+
+	name, ok := q.undo()
+	assert.True(t, ok)
+	assert.Equal(t, "repeat", name)
+	assert.Equal(t, songs[1:], q.items)
+}
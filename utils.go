@@ -7,7 +7,6 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 	"os/exec"
 	"path"
@@ -116,24 +115,14 @@ func expandTilde(_path string) string {
 	return path.Join(home, strings.TrimPrefix(_path, "~"))
 }
 
-// Detects the filetype of file
-func getFileContentType(out *os.File) (string, error) {
-
-	buffer := make([]byte, 512)
-
-	_, err := out.Read(buffer)
-	if err != nil {
-		return "", tracerr.Wrap(err)
-	}
-
-	contentType := http.DetectContentType(buffer)
-
-	return strings.SplitAfter(contentType, "/")[1], nil
-}
-
 // Gets the file name by removing extension and path
 func getName(fn string) string {
-	return strings.TrimSuffix(path.Base(fn), ".mp3")
+	base := path.Base(fn)
+	ext := path.Ext(base)
+	if !player.IsAudioExt(ext) {
+		return base
+	}
+	return strings.TrimSuffix(base, ext)
 }
 
 // This just parsing the output from the ytdl to get the audio path
@@ -248,6 +237,10 @@ func shell(input string) (string, error) {
 
 func embedLyric(songPath string, lyricTobeWritten *lyric.Lyric, isDelete bool) (err error) {
 
+	if format, ok := player.FormatOf(songPath); ok && format != "mp3" {
+		return tracerr.Errorf("unable to embed lyric to %s file", format)
+	}
+
 	var tag *id3v2.Tag
 	tag, err = id3v2.Open(songPath, id3v2.Options{Parse: true})
 	if err != nil {
@@ -340,7 +333,50 @@ func embedLength(songPath string) (time.Duration, error) {
 	return lengthSongTimeDuration, err
 }
 
+// embedReplayGain writes ReplayGain TXXX frames to the mp3 file, replacing
+// previous values.
+func embedReplayGain(songPath string, rg player.ReplayGain) error {
+	tag, err := id3v2.Open(songPath, id3v2.Options{Parse: true})
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+	defer tag.Close()
+
+	values := map[string]string{
+		player.TrackGainTag: fmt.Sprintf("%.2f dB", rg.TrackGain),
+		player.TrackPeakTag: fmt.Sprintf("%.6f", rg.TrackPeak),
+		player.AlbumGainTag: fmt.Sprintf("%.2f dB", rg.AlbumGain),
+		player.AlbumPeakTag: fmt.Sprintf("%.6f", rg.AlbumPeak),
+	}
+
+	for description, value := range values {
+		tag.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{
+			Encoding:    id3v2.EncodingUTF8,
+			Description: description,
+			Value:       value,
+		})
+	}
+
+	err = tag.Save()
+	if err != nil {
+		return tracerr.Wrap(err)
+	}
+
+	return nil
+}
+
 func getTagLength(songPath string) (songLength time.Duration, err error) {
+
+	// live stream has no length
+	if player.IsStream(songPath) {
+		return 0, nil
+	}
+
+	// only mp3 carries id3v2 tag, writing one to other formats corrupts them
+	if format, _ := player.FormatOf(songPath); format != "mp3" {
+		return player.GetLength(songPath)
+	}
+
 	var tag *id3v2.Tag
 	tag, err = id3v2.Open(songPath, id3v2.Options{Parse: true})
 	if err != nil {
@@ -376,3 +412,29 @@ func getTagLength(songPath string) (songLength time.Duration, err error) {
 
 	return songLength, err
 }
+
+// songTags is the text tags describing the song
+type songTags struct {
+	artist string
+	title  string
+	album  string
+	// number of the track in the album, zero if unknown
+	track int
+}
+
+// Gets the text tags of the song, the tags are read by the decoder of its
+// format
+func getTags(songPath string) (songTags, error) {
+
+	tags, err := player.ReadTags(songPath)
+	if err != nil {
+		return songTags{}, tracerr.Wrap(err)
+	}
+
+	return songTags{
+		artist: tags.Artist,
+		title:  tags.Title,
+		album:  tags.Album,
+		track:  tags.Track,
+	}, nil
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/issadarkthing/gomu/lyric"
+	"github.com/issadarkthing/gomu/player"
 	"github.com/stretchr/testify/assert"
 	"github.com/tramhao/id3v2"
 )
@@ -156,3 +158,49 @@ func TestEmbedLyric(t *testing.T) {
 	assert.Equal(t, lyricString, frame.Lyrics)
 	assert.Equal(t, descriptor, frame.ContentDescriptor)
 }
+
+func TestEmbedReplayGain(t *testing.T) {
+
+	testFile := filepath.Join(t.TempDir(), "sample")
+
+	f, err := os.Create(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	rg := player.ReplayGain{
+		TrackGain: -6.5,
+		TrackPeak: 0.9,
+		AlbumGain: -5.25,
+		AlbumPeak: 0.95,
+	}
+
+	// writing twice replaces the previous frames
+	for i := 0; i < 2; i++ {
+		err = embedReplayGain(testFile, rg)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tag, err := id3v2.Open(testFile, id3v2.Options{Parse: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := tag.GetFrames(tag.CommonID("User defined text information frame"))
+	tag.Close()
+	assert.Len(t, frames, 4)
+
+	got, err := player.ReadReplayGain(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, got.HasTrack)
+	assert.True(t, got.HasAlbum)
+	assert.Equal(t, rg.TrackGain, got.TrackGain)
+	assert.Equal(t, rg.TrackPeak, got.TrackPeak)
+	assert.Equal(t, rg.AlbumGain, got.AlbumGain)
+	assert.Equal(t, rg.AlbumPeak, got.AlbumPeak)
+}
@@ -0,0 +1,115 @@
+// Copyright (C) 2020  Raziman
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/issadarkthing/gomu/player"
+)
+
+// how often the spectrum is redrawn
+const visualizerInterval = time.Second / 20
+
+// bars from the lowest to the highest level
+var spectrumBars = []rune(" ▁▂▃▄▅▆▇█")
+
+// Converts levels between 0 and 1 to bars
+func spectrumStr(levels []float64) string {
+
+	var result strings.Builder
+
+	for _, level := range levels {
+		i := int(level*float64(len(spectrumBars)-1) + 0.5)
+		if i < 0 {
+			i = 0
+		} else if i >= len(spectrumBars) {
+			i = len(spectrumBars) - 1
+		}
+		result.WriteRune(spectrumBars[i])
+	}
+
+	return result.String()
+}
+
+func (p *PlayingBar) isVisualizerOn() bool {
+	return atomic.LoadInt32(&p.visualizer) != 0
+}
+
+// Shows the spectrum in place of album photo, it must be called from the UI
+// goroutine
+func (p *PlayingBar) setVisualizer(on bool) {
+
+	var value int32
+	if on {
+		value = 1
+	}
+	atomic.StoreInt32(&p.visualizer, value)
+
+	if !on {
+		p.updatePhoto()
+		// removes the spectrum, the text is left as is when nothing is
+		// playing
+		if gomu.player.State() != player.Stopped {
+			p.render()
+		}
+		return
+	}
+
+	if p.albumPhoto != nil {
+		p.albumPhoto.Clear()
+	}
+}
+
+// Stores the progress and lyric line and draws them
+func (p *PlayingBar) setText(progressText, lyricText string) {
+
+	p.mu.Lock()
+	p.progressText = progressText
+	p.lyricText = lyricText
+	p.mu.Unlock()
+
+	p.draw()
+}
+
+// Draws the progress, spectrum and lyric line from outside of the UI goroutine
+func (p *PlayingBar) draw() {
+	go gomu.app.QueueUpdateDraw(p.render)
+}
+
+// Sets the text of the progress, spectrum and lyric line, it must be called
+// from the UI goroutine
+func (p *PlayingBar) render() {
+
+	p.mu.Lock()
+	progressText := p.progressText
+	lyricText := p.lyricText
+	p.mu.Unlock()
+
+	var spectrum string
+	if p.isVisualizerOn() {
+		_, _, width, _ := p.GetInnerRect()
+		levels := gomu.player.Spectrum(width / 2)
+		r, g, b := gomu.colors.accent.RGB()
+		spectrum = fmt.Sprintf("[#%s]%s[-]", padHex(r, g, b), spectrumStr(levels))
+	}
+
+	p.text.SetText(fmt.Sprintf("%s\n%s\n%s", progressText, spectrum, lyricText))
+}
+
+// Redraws the spectrum while the song is playing, the spectrum is computed
+// here so the player is never held up by it
+func (p *PlayingBar) visualize() {
+
+	ticker := time.NewTicker(visualizerInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if p.isVisualizerOn() && gomu.player.State() == player.Playing {
+			p.draw()
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpectrumStr(t *testing.T) {
+	assert.Equal(t, "", spectrumStr(nil))
+	assert.Equal(t, " ▄█", spectrumStr([]float64{0, 0.5, 1}))
+	assert.Equal(t, " █", spectrumStr([]float64{-1, 2}))
+}